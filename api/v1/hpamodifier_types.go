@@ -1,14 +1,128 @@
 package v1
 
 import (
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// ForecasterType 负载预测器类型
+// +kubebuilder:validation:Enum=External;HoltWinters;ARIMA
+type ForecasterType string
+
+const (
+	// ForecasterExternal 调用外部预测服务
+	ForecasterExternal ForecasterType = "External"
+	// ForecasterHoltWinters 内置的加性季节 Holt-Winters 模型
+	ForecasterHoltWinters ForecasterType = "HoltWinters"
+	// ForecasterARIMA 内置的简化 ARIMA 模型
+	ForecasterARIMA ForecasterType = "ARIMA"
+)
+
+// NativeHPAMode 目标已被原生 HorizontalPodAutoscaler 管理时的处理方式
+// +kubebuilder:validation:Enum=Refuse;Modify
+type NativeHPAMode string
+
+const (
+	// NativeHPARefuse 不伸缩，并设置 NativeHPAConflict 条件
+	NativeHPARefuse NativeHPAMode = "Refuse"
+	// NativeHPAModify 根据预测调整原生 HPA 的 minReplicas/maxReplicas，不直接修改副本数
+	NativeHPAModify NativeHPAMode = "Modify"
+)
+
+// ThresholdMode CPUThreshold 和 MemoryThreshold 的含义
+// +kubebuilder:validation:Enum=Absolute;Utilization
+type ThresholdMode string
+
+const (
+	// ThresholdModeAbsolute 阈值为每个 Pod 的平均用量，CPU 以核、内存以 GiB 为单位
+	ThresholdModeAbsolute ThresholdMode = "Absolute"
+	// ThresholdModeUtilization 阈值为用量占容器 requests 的比例，与原生 HPA 的利用率相同，如 0.7 表示 70%
+	ThresholdModeUtilization ThresholdMode = "Utilization"
+)
+
+// MissingRequestsPolicy 按利用率计算时，如何处理没有设置 requests 的容器
+// +kubebuilder:validation:Enum=Fail;IgnoreContainer
+type MissingRequestsPolicy string
+
+const (
+	// MissingRequestsFail 与原生 HPA 相同，任一容器缺少 requests 时无法计算利用率，不伸缩
+	MissingRequestsFail MissingRequestsPolicy = "Fail"
+	// MissingRequestsIgnoreContainer 缺少 requests 的容器不计入该资源的用量和 requests，适合不设 requests 的 sidecar
+	MissingRequestsIgnoreContainer MissingRequestsPolicy = "IgnoreContainer"
+)
+
+// AggregationFunction 跨 Pod 汇总用量的方式
+// +kubebuilder:validation:Enum=Mean;Max;Median;P90;P95
+type AggregationFunction string
+
+const (
+	// AggregationMean 所有 Pod 的平均值；按利用率计算时与原生 HPA 相同，为用量之和除以 requests 之和
+	AggregationMean AggregationFunction = "Mean"
+	// AggregationMax 用量最高的 Pod，适合负载按分片集中在个别 Pod 上的工作负载
+	AggregationMax AggregationFunction = "Max"
+	// AggregationMedian 所有 Pod 的中位数，不受个别异常 Pod 的影响
+	AggregationMedian AggregationFunction = "Median"
+	// AggregationP90 所有 Pod 的第 90 百分位数
+	AggregationP90 AggregationFunction = "P90"
+	// AggregationP95 所有 Pod 的第 95 百分位数
+	AggregationP95 AggregationFunction = "P95"
+)
+
+// ContainerSelector 按容器名选择计入用量的容器
+type ContainerSelector struct {
+	// Include 只计入这些容器，为空时计入所有容器
+	// +optional
+	Include []string `json:"include,omitempty"`
+	// Exclude 不计入这些容器，如 istio-proxy 等 sidecar
+	// +optional
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// MetricFreshness 单个 Pod 指标样本的新鲜度限制，超出限制的样本不计入，也不写入历史数据
+type MetricFreshness struct {
+	// MaxAgeSeconds 样本时间戳距当前的最长时间（秒），为 0 时使用默认的 120
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxAgeSeconds int32 `json:"maxAgeSeconds,omitempty"`
+	// MaxWindowSeconds 样本统计窗口的最大长度（秒），为 0 时使用默认的 300；窗口过长的样本无法反映当前负载
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxWindowSeconds int32 `json:"maxWindowSeconds,omitempty"`
+}
+
+// MetricsSourceType 获取 Pod 用量的指标来源
+// +kubebuilder:validation:Enum=MetricsServer;Prometheus
+type MetricsSourceType string
+
+const (
+	// MetricsSourceMetricsServer 通过 metrics.k8s.io 读取 metrics-server 的瞬时用量
+	MetricsSourceMetricsServer MetricsSourceType = "MetricsServer"
+	// MetricsSourcePrometheus 执行 PromQL 查询，需要控制器配置 --prometheus-url
+	MetricsSourcePrometheus MetricsSourceType = "Prometheus"
+)
+
+// PrometheusQueries 覆盖控制器默认的 PromQL 查询模板。
+// 模板使用 Go text/template 语法，可引用 .Namespace、.Workload、.Kind、.Selector 和 .LabelMatchers，
+// 查询结果需按 pod 标签返回每个 Pod 一条序列
+type PrometheusQueries struct {
+	// CPU 返回每个 Pod CPU 用量（核）的查询
+	// +optional
+	CPU string `json:"cpu,omitempty"`
+	// Memory 返回每个 Pod 内存用量（字节）的查询
+	// +optional
+	Memory string `json:"memory,omitempty"`
+}
+
 // HPAModifierSpec 定义 HPAModifier 的期望状态
 type HPAModifierSpec struct {
-	// TargetRef 指定要伸缩的工作负载（如 Deployment）
+	// TargetRef 指定要伸缩的工作负载，支持任何暴露 /scale 子资源的类型
+	// （Deployment、StatefulSet、ReplicaSet 或自定义资源），未指定 Kind 时默认为 apps/v1 Deployment
 	TargetRef corev1.ObjectReference `json:"targetRef"`
+	// Selector 可选，覆盖用于匹配目标 Pod 的标签选择器；
+	// 未设置时使用目标 /scale 子资源 status 中的 selector
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
 	// MinReplicas 最小副本数
 	MinReplicas int32 `json:"minReplicas"`
 	// MaxReplicas 最大副本数
@@ -17,19 +131,205 @@ type HPAModifierSpec struct {
 	CPUThreshold float64 `json:"cpuThreshold"`
 	// MemoryThreshold 内存使用率阈值，触发伸缩
 	MemoryThreshold float64 `json:"memoryThreshold"`
-	// PredictionWindow ARIMA 预测时间窗口（秒）
+	// ThresholdMode 阈值的含义，默认 Absolute；Utilization 时按用量占容器 requests 的比例比较
+	// +optional
+	ThresholdMode ThresholdMode `json:"thresholdMode,omitempty"`
+	// MissingRequests 按利用率计算时如何处理没有设置 requests 的容器，默认 Fail
+	// +optional
+	MissingRequests MissingRequestsPolicy `json:"missingRequests,omitempty"`
+	// Tolerance 负载比率与 1 的差不超过该值时不伸缩，与原生 HPA 的 --horizontal-pod-autoscaler-tolerance 相同，默认 0.1
+	// +optional
+	Tolerance *float64 `json:"tolerance,omitempty"`
+	// ScaleDownHysteresis 缩容在 Tolerance 之外额外需要的负载比率余量，默认 0。
+	// 例如 Tolerance 为 0.1、ScaleDownHysteresis 为 0.1 时，负载比率高于 1.1 才扩容，低于 0.8 才缩容，
+	// 避免负载在阈值附近波动时反复扩缩
+	// +optional
+	ScaleDownHysteresis *float64 `json:"scaleDownHysteresis,omitempty"`
+	// Aggregation 跨 Pod 汇总 CPU 和内存用量的方式，默认 Mean；
+	// 负载在 Pod 间不均衡时可按 Max 或 P90/P95 伸缩，避免平均值掩盖过热的 Pod
+	// +optional
+	Aggregation AggregationFunction `json:"aggregation,omitempty"`
+	// Containers 可选，按容器名选择计入 CPU 和内存用量的容器，未设置时计入 Pod 的所有容器
+	// +optional
+	Containers *ContainerSelector `json:"containers,omitempty"`
+	// MetricFreshness 可选，覆盖默认的指标新鲜度限制，metrics-server 延迟较大时避免按过期的用量伸缩
+	// +optional
+	MetricFreshness *MetricFreshness `json:"metricFreshness,omitempty"`
+	// PodInitializationPeriod Pod 启动后多少秒内检查就绪时间：样本的统计窗口包含就绪之前的时间时不计入，
+	// 避免预热期间的用量峰值引起震荡，与原生 HPA 的 --horizontal-pod-autoscaler-cpu-initialization-period 相同，默认 300
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	PodInitializationPeriod *int32 `json:"podInitializationPeriod,omitempty"`
+	// Metrics 可选，语义与 autoscaling/v2 HPA 的 metrics 相同，支持 Resource、Pods、Object 和 External 指标；
+	// 每个指标单独预测并给出副本数建议，取其中最大的。设置后不再使用 CPUThreshold 和 MemoryThreshold
+	// +optional
+	Metrics []autoscalingv2.MetricSpec `json:"metrics,omitempty"`
+	// PredictionWindow ARIMA 预测时间窗口（秒），作为预测请求的 horizon 发送给预测服务
 	PredictionWindow int32 `json:"predictionWindow"`
+	// Forecaster 负载预测器，默认使用外部预测服务；
+	// HoltWinters 和 ARIMA 在控制器进程内基于历史数据预测，无需部署预测服务
+	// +optional
+	Forecaster ForecasterType `json:"forecaster,omitempty"`
+	// Behavior 扩容和缩容各自的稳定窗口与速率策略，语义与 autoscaling/v2 HPA 相同，
+	// 在策略算出期望副本数之后生效；设置后不再使用策略的伸缩延迟，未设置的方向使用 HPA 的默认规则
+	// +optional
+	Behavior *autoscalingv2.HorizontalPodAutoscalerBehavior `json:"behavior,omitempty"`
+	// NativeHPA 目标已有 autoscaling/v2 HPA 时的处理方式，默认 Refuse，避免两个控制器争抢 spec.replicas
+	// +optional
+	NativeHPA NativeHPAMode `json:"nativeHPA,omitempty"`
+	// MetricsSource 指标来源，默认 MetricsServer
+	// +optional
+	MetricsSource MetricsSourceType `json:"metricsSource,omitempty"`
+	// PrometheusQueries 可选，MetricsSource 为 Prometheus 时覆盖默认的查询模板
+	// +optional
+	PrometheusQueries *PrometheusQueries `json:"prometheusQueries,omitempty"`
+}
+
+// HPAModifier 状态条件类型
+const (
+	// ConditionReady 最近一次调谐是否成功完成
+	ConditionReady = "Ready"
+	// ConditionAbleToScale 是否能够读取和更新目标的 /scale 子资源
+	ConditionAbleToScale = "AbleToScale"
+	// ConditionScalingLimited 期望副本数是否被 MinReplicas/MaxReplicas 或 behavior 的速率策略限制
+	ConditionScalingLimited = "ScalingLimited"
+	// ConditionMetricsAvailable 是否成功获取目标 Pod 的指标
+	ConditionMetricsAvailable = "MetricsAvailable"
+	// ConditionPredictionAvailable 是否成功获取负载预测
+	ConditionPredictionAvailable = "PredictionAvailable"
+	// ConditionPredictorUnavailable 预测不可用，控制器按实时用量被动伸缩
+	ConditionPredictorUnavailable = "PredictorUnavailable"
+	// ConditionMetricsStale 最近一次收集的指标中是否有过期或重复的样本
+	ConditionMetricsStale = "MetricsStale"
+	// ConditionNativeHPAConflict 目标已被原生 HPA 管理，控制器拒绝直接伸缩
+	ConditionNativeHPAConflict = "NativeHPAConflict"
+)
+
+// HPAModifier 状态条件原因
+const (
+	ReasonReconciled          = "Reconciled"
+	ReasonReconcileFailed     = "ReconcileFailed"
+	ReasonSucceededRescale    = "SucceededRescale"
+	ReasonReadyForNewScale    = "ReadyForNewScale"
+	ReasonFailedGetScale      = "FailedGetScale"
+	ReasonFailedUpdateScale   = "FailedUpdateScale"
+	ReasonBackoff             = "Backoff"
+	ReasonTooFewReplicas      = "TooFewReplicas"
+	ReasonTooManyReplicas     = "TooManyReplicas"
+	ReasonDesiredWithinRange  = "DesiredWithinRange"
+	ReasonScaleUpLimit        = "ScaleUpLimit"
+	ReasonScaleDownLimit      = "ScaleDownLimit"
+	ReasonScaleUpStabilized   = "ScaleUpStabilized"
+	ReasonScaleDownStabilized = "ScaleDownStabilized"
+	ReasonNoNativeHPA         = "NoNativeHPA"
+	ReasonNativeHPAFound      = "NativeHPAFound"
+	ReasonModifiedNativeHPA   = "ModifiedNativeHPA"
+	ReasonMetricsCollected    = "MetricsCollected"
+	ReasonFailedGetMetrics    = "FailedGetMetrics"
+	ReasonMetricsFresh        = "MetricsFresh"
+	ReasonStaleSamples        = "StaleSamples"
+	ReasonDuplicateSample     = "DuplicateSample"
+	ReasonPredictionFailed    = "PredictionFailed"
+	ReasonPredictionSucceeded = "PredictionSucceeded"
+)
+
+// PodExclusions 收集指标时按 Pod 状态排除的 Pod 数量
+type PodExclusions struct {
+	// Unready 未就绪的 Pod
+	// +optional
+	Unready int32 `json:"unready,omitempty"`
+	// Initializing 仍在 podInitializationPeriod 内、且在样本统计窗口内才就绪的 Pod
+	// +optional
+	Initializing int32 `json:"initializing,omitempty"`
+	// Terminating 正在删除的 Pod
+	// +optional
+	Terminating int32 `json:"terminating,omitempty"`
+	// Finished 已失败或已完成的 Pod
+	// +optional
+	Finished int32 `json:"finished,omitempty"`
+	// Stale 样本过期或统计窗口超出 metricFreshness 限制的 Pod
+	// +optional
+	Stale int32 `json:"stale,omitempty"`
+}
+
+// PreWarmPlan 根据预测计划的下一次预热扩容
+type PreWarmPlan struct {
+	// ScaleUpTime 计划开始扩容的时间，为预测峰值时间减去 Pod 启动耗时
+	ScaleUpTime metav1.Time `json:"scaleUpTime"`
+	// PeakTime 预测的负载峰值时间
+	PeakTime metav1.Time `json:"peakTime"`
+	// Replicas 峰值到来时需要的副本数
+	Replicas int32 `json:"replicas"`
 }
 
 // HPAModifierStatus 定义 HPAModifier 的当前状态
 type HPAModifierStatus struct {
+	// CurrentReplicas 最近一次调谐时目标 /scale 子资源的 spec.replicas，期望副本数以此为基数计算
 	CurrentReplicas int32        `json:"currentReplicas"`
 	PredictedLoad   float64      `json:"predictedLoad"`
 	LastScaledTime  *metav1.Time `json:"lastScaledTime"`
+	// ReadyReplicas 最近一次收集指标时已就绪且不在初始化的目标 Pod 数量
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+	// StartingReplicas 最近一次收集指标时仍在 podInitializationPeriod 内启动的目标 Pod 数量，
+	// 只有这些 Pod 会使缩容暂停，超过初始化时间仍未就绪的 Pod 不算在内
+	// +optional
+	StartingReplicas int32 `json:"startingReplicas,omitempty"`
+	// LastAppliedReplicas 控制器最近一次写入目标 /scale 子资源的副本数
+	// +optional
+	LastAppliedReplicas int32 `json:"lastAppliedReplicas,omitempty"`
+	// ReplicaDrift 目标当前的 spec.replicas 与 LastAppliedReplicas 之差，
+	// 不为 0 说明副本数在控制器之外被修改，如手动伸缩或其他控制器
+	// +optional
+	ReplicaDrift int32 `json:"replicaDrift,omitempty"`
+	// ObservedGeneration 控制器最近一次处理的 spec 版本
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// DesiredReplicas 控制器最近一次计算出的期望副本数
+	// +optional
+	DesiredReplicas int32 `json:"desiredReplicas,omitempty"`
+	// MetricPods 最近一次计入指标的 Pod 数量
+	// +optional
+	MetricPods int32 `json:"metricPods,omitempty"`
+	// ExcludedPods 最近一次收集指标时按 Pod 状态排除的 Pod 数量
+	// +optional
+	ExcludedPods PodExclusions `json:"excludedPods,omitempty"`
+	// SuppressedDecisions 负载比率落在容差带内、未改变副本数的伸缩决策累计次数
+	// +optional
+	SuppressedDecisions int32 `json:"suppressedDecisions,omitempty"`
+	// NextPreWarm 下一次计划的预热扩容，没有计划时为空
+	// +optional
+	NextPreWarm *PreWarmPlan `json:"nextPreWarm,omitempty"`
+	// PodStartupLatency 从目标 Pod 创建到就绪耗时学习到的 P90，作为预热扩容的提前量，尚未观测到时为空
+	// +optional
+	PodStartupLatency *metav1.Duration `json:"podStartupLatency,omitempty"`
+	// LastSampleTime 最近一次计入的指标样本中最新的时间戳，用于识别指标来源未更新时的重复样本
+	// +optional
+	LastSampleTime *metav1.Time `json:"lastSampleTime,omitempty"`
+	// WorkloadPattern 识别出的工作负载模式：Stable、Periodic 或 Burst
+	// +optional
+	WorkloadPattern string `json:"workloadPattern,omitempty"`
+	// Strategy 当前生效的伸缩策略
+	// +optional
+	Strategy string `json:"strategy,omitempty"`
+	// Conditions HPAModifier 的状态条件
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Target",type=string,JSONPath=`.spec.targetRef.name`
+//+kubebuilder:printcolumn:name="Min",type=integer,JSONPath=`.spec.minReplicas`
+//+kubebuilder:printcolumn:name="Max",type=integer,JSONPath=`.spec.maxReplicas`
+//+kubebuilder:printcolumn:name="Current",type=integer,JSONPath=`.status.currentReplicas`
+//+kubebuilder:printcolumn:name="Desired",type=integer,JSONPath=`.status.desiredReplicas`
+//+kubebuilder:printcolumn:name="Pattern",type=string,JSONPath=`.status.workloadPattern`
+//+kubebuilder:printcolumn:name="Strategy",type=string,JSONPath=`.status.strategy`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // HPAModifier 是 hpamodifiers API 的模式
 type HPAModifier struct {
@@ -0,0 +1,449 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"text/template"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// 未设置时由 webhook 填充的默认值
+const (
+	DefaultMinReplicas      int32   = 1
+	DefaultCPUThreshold     float64 = 0.7
+	DefaultMemoryThreshold  float64 = 0.8
+	DefaultPredictionWindow int32   = 300
+	DefaultTolerance        float64 = 0.1
+)
+
+// 与 autoscaling/v2 HPA 相同的 behavior 取值上限
+const (
+	maxStabilizationWindowSeconds = 3600
+	maxPolicyPeriodSeconds        = 1800
+)
+
+// SetupWebhookWithManager 注册 HPAModifier 的默认值和校验 webhook
+func (r *HPAModifier) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithDefaulter(&HPAModifierDefaulter{}).
+		WithValidator(&HPAModifierValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-autoscaling-yemo-info-v1-hpamodifier,mutating=true,failurePolicy=fail,sideEffects=None,groups=autoscaling.yemo.info,resources=hpamodifiers,verbs=create;update,versions=v1,name=mhpamodifier.kb.io,admissionReviewVersions=v1
+
+// HPAModifierDefaulter 为 HPAModifier 填充默认值
+// +kubebuilder:object:generate=false
+type HPAModifierDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &HPAModifierDefaulter{}
+
+// Default 填充阈值、预测窗口、最小副本数等未设置字段的默认值
+func (d *HPAModifierDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	hpa, ok := obj.(*HPAModifier)
+	if !ok {
+		return fmt.Errorf("expected an HPAModifier but got %T", obj)
+	}
+
+	if hpa.Spec.MinReplicas == 0 {
+		hpa.Spec.MinReplicas = DefaultMinReplicas
+	}
+	if hpa.Spec.CPUThreshold == 0 {
+		hpa.Spec.CPUThreshold = DefaultCPUThreshold
+	}
+	if hpa.Spec.MemoryThreshold == 0 {
+		hpa.Spec.MemoryThreshold = DefaultMemoryThreshold
+	}
+	if hpa.Spec.PredictionWindow == 0 {
+		hpa.Spec.PredictionWindow = DefaultPredictionWindow
+	}
+	if hpa.Spec.Forecaster == "" {
+		hpa.Spec.Forecaster = ForecasterExternal
+	}
+	if hpa.Spec.NativeHPA == "" {
+		hpa.Spec.NativeHPA = NativeHPARefuse
+	}
+	if hpa.Spec.ThresholdMode == "" {
+		hpa.Spec.ThresholdMode = ThresholdModeAbsolute
+	}
+	if hpa.Spec.MissingRequests == "" {
+		hpa.Spec.MissingRequests = MissingRequestsFail
+	}
+	if hpa.Spec.Tolerance == nil {
+		tolerance := DefaultTolerance
+		hpa.Spec.Tolerance = &tolerance
+	}
+	if hpa.Spec.Aggregation == "" {
+		hpa.Spec.Aggregation = AggregationMean
+	}
+	if hpa.Spec.MetricsSource == "" {
+		hpa.Spec.MetricsSource = MetricsSourceMetricsServer
+	}
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-autoscaling-yemo-info-v1-hpamodifier,mutating=false,failurePolicy=fail,sideEffects=None,groups=autoscaling.yemo.info,resources=hpamodifiers,verbs=create;update,versions=v1,name=vhpamodifier.kb.io,admissionReviewVersions=v1
+
+// HPAModifierValidator 校验 HPAModifier，并拒绝与已有 HPAModifier 指向同一工作负载的对象
+// +kubebuilder:object:generate=false
+type HPAModifierValidator struct {
+	Client client.Reader
+}
+
+var _ webhook.CustomValidator = &HPAModifierValidator{}
+
+// ValidateCreate 校验新建的 HPAModifier
+func (v *HPAModifierValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	hpa, ok := obj.(*HPAModifier)
+	if !ok {
+		return nil, fmt.Errorf("expected an HPAModifier but got %T", obj)
+	}
+	return nil, v.validate(ctx, hpa, nil)
+}
+
+// ValidateUpdate 校验更新后的 HPAModifier
+func (v *HPAModifierValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	hpa, ok := newObj.(*HPAModifier)
+	if !ok {
+		return nil, fmt.Errorf("expected an HPAModifier but got %T", newObj)
+	}
+	old, ok := oldObj.(*HPAModifier)
+	if !ok {
+		return nil, fmt.Errorf("expected an HPAModifier but got %T", oldObj)
+	}
+	return nil, v.validate(ctx, hpa, old)
+}
+
+// ValidateDelete 删除不做校验
+func (v *HPAModifierValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate 汇总所有字段错误，有错误时返回 Invalid。old 为更新前的对象，新建时为 nil
+func (v *HPAModifierValidator) validate(ctx context.Context, hpa, old *HPAModifier) error {
+	allErrs := ValidateHPAModifierSpec(hpa)
+
+	conflictErrs, err := v.validateUniqueTarget(ctx, hpa, old)
+	if err != nil {
+		return apierrors.NewInternalError(err)
+	}
+	allErrs = append(allErrs, conflictErrs...)
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("HPAModifier").GroupKind(), hpa.Name, allErrs)
+}
+
+// ValidateHPAModifierSpec 校验 spec 中与其他对象无关的字段
+func ValidateHPAModifierSpec(hpa *HPAModifier) field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+	spec := hpa.Spec
+
+	targetPath := specPath.Child("targetRef")
+	if spec.TargetRef.Name == "" {
+		allErrs = append(allErrs, field.Required(targetPath.Child("name"), "the workload to scale must be set"))
+	}
+	if spec.TargetRef.Namespace != "" && spec.TargetRef.Namespace != hpa.Namespace {
+		allErrs = append(allErrs, field.Invalid(targetPath.Child("namespace"), spec.TargetRef.Namespace,
+			fmt.Sprintf("must be empty or equal to the HPAModifier namespace %q", hpa.Namespace)))
+	}
+	if _, err := schema.ParseGroupVersion(spec.TargetRef.APIVersion); err != nil {
+		allErrs = append(allErrs, field.Invalid(targetPath.Child("apiVersion"), spec.TargetRef.APIVersion, err.Error()))
+	}
+
+	if spec.MinReplicas < 1 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("minReplicas"), spec.MinReplicas, "must be at least 1"))
+	}
+	if spec.MaxReplicas < spec.MinReplicas {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("maxReplicas"), spec.MaxReplicas,
+			fmt.Sprintf("must be greater than or equal to minReplicas %d", spec.MinReplicas)))
+	}
+	if spec.CPUThreshold <= 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("cpuThreshold"), spec.CPUThreshold, "must be greater than 0"))
+	}
+	if spec.MemoryThreshold <= 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("memoryThreshold"), spec.MemoryThreshold, "must be greater than 0"))
+	}
+	if spec.PodInitializationPeriod != nil && *spec.PodInitializationPeriod < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("podInitializationPeriod"), *spec.PodInitializationPeriod, "must not be negative"))
+	}
+	if spec.Tolerance != nil && (*spec.Tolerance < 0 || *spec.Tolerance >= 1) {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("tolerance"), *spec.Tolerance, "must be at least 0 and less than 1"))
+	}
+	if spec.ScaleDownHysteresis != nil {
+		hysteresisPath := specPath.Child("scaleDownHysteresis")
+		tolerance := DefaultTolerance
+		if spec.Tolerance != nil {
+			tolerance = *spec.Tolerance
+		}
+		if *spec.ScaleDownHysteresis < 0 {
+			allErrs = append(allErrs, field.Invalid(hysteresisPath, *spec.ScaleDownHysteresis, "must not be negative"))
+		} else if tolerance+*spec.ScaleDownHysteresis >= 1 {
+			allErrs = append(allErrs, field.Invalid(hysteresisPath, *spec.ScaleDownHysteresis,
+				"tolerance plus scaleDownHysteresis must be less than 1, otherwise the workload never scales down"))
+		}
+	}
+	if freshness := spec.MetricFreshness; freshness != nil {
+		freshnessPath := specPath.Child("metricFreshness")
+		if freshness.MaxAgeSeconds < 0 {
+			allErrs = append(allErrs, field.Invalid(freshnessPath.Child("maxAgeSeconds"), freshness.MaxAgeSeconds, "must not be negative"))
+		}
+		if freshness.MaxWindowSeconds < 0 {
+			allErrs = append(allErrs, field.Invalid(freshnessPath.Child("maxWindowSeconds"), freshness.MaxWindowSeconds, "must not be negative"))
+		}
+	}
+	if spec.Containers != nil {
+		allErrs = append(allErrs, validateContainerSelector(spec.Containers, specPath.Child("containers"))...)
+	}
+	if spec.PredictionWindow < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("predictionWindow"), spec.PredictionWindow, "must not be negative"))
+	}
+
+	metricsPath := specPath.Child("metrics")
+	for i, metric := range spec.Metrics {
+		allErrs = append(allErrs, validateMetricSpec(metric, metricsPath.Index(i))...)
+	}
+
+	if queries := spec.PrometheusQueries; queries != nil {
+		queriesPath := specPath.Child("prometheusQueries")
+		if spec.MetricsSource != MetricsSourcePrometheus {
+			allErrs = append(allErrs, field.Forbidden(queriesPath, "may only be set when metricsSource is Prometheus"))
+		}
+		allErrs = append(allErrs, validateQueryTemplate(queries.CPU, queriesPath.Child("cpu"))...)
+		allErrs = append(allErrs, validateQueryTemplate(queries.Memory, queriesPath.Child("memory"))...)
+	}
+
+	if spec.Behavior != nil {
+		behaviorPath := specPath.Child("behavior")
+		allErrs = append(allErrs, validateScalingRules(spec.Behavior.ScaleUp, behaviorPath.Child("scaleUp"))...)
+		allErrs = append(allErrs, validateScalingRules(spec.Behavior.ScaleDown, behaviorPath.Child("scaleDown"))...)
+	}
+	return allErrs
+}
+
+// validateContainerSelector 校验容器名非空，且同一容器不能同时出现在 include 和 exclude 中
+func validateContainerSelector(selector *ContainerSelector, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	included := make(map[string]bool, len(selector.Include))
+	for i, name := range selector.Include {
+		if name == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("include").Index(i), "container name must not be empty"))
+		}
+		included[name] = true
+	}
+	for i, name := range selector.Exclude {
+		if name == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("exclude").Index(i), "container name must not be empty"))
+		} else if included[name] {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("exclude").Index(i), name, "container is also listed in include"))
+		}
+	}
+	return allErrs
+}
+
+// validateMetricSpec 校验 spec.metrics 中的一个指标，只允许与类型对应的来源字段和支持的目标类型
+func validateMetricSpec(metric autoscalingv2.MetricSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	var name string
+	var target *autoscalingv2.MetricTarget
+	var targetPath *field.Path
+	var supported []autoscalingv2.MetricTargetType
+	switch metric.Type {
+	case autoscalingv2.ResourceMetricSourceType:
+		if metric.Resource == nil {
+			return append(allErrs, field.Required(fldPath.Child("resource"), "must be set for Resource metrics"))
+		}
+		if metric.Resource.Name != corev1.ResourceCPU && metric.Resource.Name != corev1.ResourceMemory {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("resource", "name"), metric.Resource.Name,
+				[]string{string(corev1.ResourceCPU), string(corev1.ResourceMemory)}))
+		}
+		name, target, targetPath = string(metric.Resource.Name), &metric.Resource.Target, fldPath.Child("resource", "target")
+		supported = []autoscalingv2.MetricTargetType{autoscalingv2.UtilizationMetricType, autoscalingv2.AverageValueMetricType}
+	case autoscalingv2.PodsMetricSourceType:
+		if metric.Pods == nil {
+			return append(allErrs, field.Required(fldPath.Child("pods"), "must be set for Pods metrics"))
+		}
+		name, target, targetPath = metric.Pods.Metric.Name, &metric.Pods.Target, fldPath.Child("pods", "target")
+		supported = []autoscalingv2.MetricTargetType{autoscalingv2.AverageValueMetricType}
+	case autoscalingv2.ObjectMetricSourceType:
+		if metric.Object == nil {
+			return append(allErrs, field.Required(fldPath.Child("object"), "must be set for Object metrics"))
+		}
+		if metric.Object.DescribedObject.Kind == "" || metric.Object.DescribedObject.Name == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("object", "describedObject"), "kind and name must be set"))
+		}
+		name, target, targetPath = metric.Object.Metric.Name, &metric.Object.Target, fldPath.Child("object", "target")
+		supported = []autoscalingv2.MetricTargetType{autoscalingv2.ValueMetricType, autoscalingv2.AverageValueMetricType}
+	case autoscalingv2.ExternalMetricSourceType:
+		if metric.External == nil {
+			return append(allErrs, field.Required(fldPath.Child("external"), "must be set for External metrics"))
+		}
+		name, target, targetPath = metric.External.Metric.Name, &metric.External.Target, fldPath.Child("external", "target")
+		supported = []autoscalingv2.MetricTargetType{autoscalingv2.ValueMetricType, autoscalingv2.AverageValueMetricType}
+	default:
+		return append(allErrs, field.NotSupported(fldPath.Child("type"), metric.Type, []string{
+			string(autoscalingv2.ResourceMetricSourceType), string(autoscalingv2.PodsMetricSourceType),
+			string(autoscalingv2.ObjectMetricSourceType), string(autoscalingv2.ExternalMetricSourceType),
+		}))
+	}
+
+	if name == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("metric", "name"), "the metric name must be set"))
+	}
+
+	var value *resource.Quantity
+	switch target.Type {
+	case autoscalingv2.ValueMetricType:
+		value = target.Value
+	case autoscalingv2.AverageValueMetricType:
+		value = target.AverageValue
+	case autoscalingv2.UtilizationMetricType:
+		if target.AverageUtilization != nil {
+			value = resource.NewQuantity(int64(*target.AverageUtilization), resource.DecimalSI)
+		}
+	}
+	isSupported := false
+	for _, targetType := range supported {
+		isSupported = isSupported || target.Type == targetType
+	}
+	if !isSupported {
+		values := make([]string, len(supported))
+		for i, targetType := range supported {
+			values[i] = string(targetType)
+		}
+		allErrs = append(allErrs, field.NotSupported(targetPath.Child("type"), target.Type, values))
+	} else if value == nil {
+		allErrs = append(allErrs, field.Required(targetPath, fmt.Sprintf("a target value must be set for target type %s", target.Type)))
+	} else if value.Sign() <= 0 {
+		allErrs = append(allErrs, field.Invalid(targetPath, value.String(), "must be greater than 0"))
+	}
+	return allErrs
+}
+
+// validateQueryTemplate 校验 PromQL 查询模板的语法，空模板表示使用控制器的默认查询
+func validateQueryTemplate(query string, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if query == "" {
+		return allErrs
+	}
+	if _, err := template.New(fldPath.String()).Parse(query); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath, query, err.Error()))
+	}
+	return allErrs
+}
+
+// validateScalingRules 校验一个方向的 behavior 规则
+func validateScalingRules(rules *autoscalingv2.HPAScalingRules, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if rules == nil {
+		return allErrs
+	}
+
+	if window := rules.StabilizationWindowSeconds; window != nil && (*window < 0 || *window > maxStabilizationWindowSeconds) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("stabilizationWindowSeconds"), *window,
+			fmt.Sprintf("must be between 0 and %d", maxStabilizationWindowSeconds)))
+	}
+	if rules.SelectPolicy != nil {
+		switch *rules.SelectPolicy {
+		case autoscalingv2.MaxChangePolicySelect, autoscalingv2.MinChangePolicySelect, autoscalingv2.DisabledPolicySelect:
+		default:
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("selectPolicy"), *rules.SelectPolicy,
+				[]string{string(autoscalingv2.MaxChangePolicySelect), string(autoscalingv2.MinChangePolicySelect), string(autoscalingv2.DisabledPolicySelect)}))
+		}
+	}
+	for i, policy := range rules.Policies {
+		policyPath := fldPath.Child("policies").Index(i)
+		if policy.Type != autoscalingv2.PodsScalingPolicy && policy.Type != autoscalingv2.PercentScalingPolicy {
+			allErrs = append(allErrs, field.NotSupported(policyPath.Child("type"), policy.Type,
+				[]string{string(autoscalingv2.PodsScalingPolicy), string(autoscalingv2.PercentScalingPolicy)}))
+		}
+		if policy.Value <= 0 {
+			allErrs = append(allErrs, field.Invalid(policyPath.Child("value"), policy.Value, "must be greater than 0"))
+		}
+		if policy.PeriodSeconds <= 0 || policy.PeriodSeconds > maxPolicyPeriodSeconds {
+			allErrs = append(allErrs, field.Invalid(policyPath.Child("periodSeconds"), policy.PeriodSeconds,
+				fmt.Sprintf("must be between 1 and %d", maxPolicyPeriodSeconds)))
+		}
+	}
+	return allErrs
+}
+
+// validateUniqueTarget 拒绝与同命名空间中其他 HPAModifier 指向同一工作负载。
+// 更新时只在 targetRef 改为其他工作负载时检查，启用 webhook 前已存在的重复对象仍可修改其他字段
+func (v *HPAModifierValidator) validateUniqueTarget(ctx context.Context, hpa, old *HPAModifier) (field.ErrorList, error) {
+	var allErrs field.ErrorList
+	if v.Client == nil {
+		return allErrs, nil
+	}
+	target := targetIdentity(hpa.Spec.TargetRef.APIVersion, hpa.Spec.TargetRef.Kind, hpa.Spec.TargetRef.Name)
+	if old != nil && targetIdentity(old.Spec.TargetRef.APIVersion, old.Spec.TargetRef.Kind, old.Spec.TargetRef.Name) == target {
+		return allErrs, nil
+	}
+
+	list := &HPAModifierList{}
+	if err := v.Client.List(ctx, list, client.InNamespace(hpa.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list HPAModifiers: %v", err)
+	}
+
+	for _, other := range list.Items {
+		if other.Name == hpa.Name {
+			continue
+		}
+		if targetIdentity(other.Spec.TargetRef.APIVersion, other.Spec.TargetRef.Kind, other.Spec.TargetRef.Name) == target {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "targetRef"),
+				fmt.Sprintf("%s %q is already scaled by HPAModifier %q", target.Kind, target.Name, other.Name)))
+		}
+	}
+	return allErrs, nil
+}
+
+// workloadIdentity 用 group、kind 和名称标识一个工作负载，忽略 API 版本
+// +kubebuilder:object:generate=false
+type workloadIdentity struct {
+	Group string
+	Kind  string
+	Name  string
+}
+
+// targetIdentity 返回 TargetRef 指向的工作负载，未指定 Kind 时按 apps/v1 Deployment 处理
+func targetIdentity(apiVersion, kind, name string) workloadIdentity {
+	if kind == "" {
+		kind = "Deployment"
+		if apiVersion == "" {
+			apiVersion = "apps/v1"
+		}
+	}
+	gv, _ := schema.ParseGroupVersion(apiVersion)
+	return workloadIdentity{Group: gv.Group, Kind: kind, Name: name}
+}
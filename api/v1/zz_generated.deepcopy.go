@@ -21,16 +21,43 @@ limitations under the License.
 package v1
 
 import (
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerSelector) DeepCopyInto(out *ContainerSelector) {
+	*out = *in
+	if in.Include != nil {
+		in, out := &in.Include, &out.Include
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Exclude != nil {
+		in, out := &in.Exclude, &out.Exclude
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerSelector.
+func (in *ContainerSelector) DeepCopy() *ContainerSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HPAModifier) DeepCopyInto(out *HPAModifier) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HPAModifier.
@@ -86,6 +113,54 @@ func (in *HPAModifierList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HPAModifierSpec) DeepCopyInto(out *HPAModifierSpec) {
 	*out = *in
+	out.TargetRef = in.TargetRef
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tolerance != nil {
+		in, out := &in.Tolerance, &out.Tolerance
+		*out = new(float64)
+		**out = **in
+	}
+	if in.ScaleDownHysteresis != nil {
+		in, out := &in.ScaleDownHysteresis, &out.ScaleDownHysteresis
+		*out = new(float64)
+		**out = **in
+	}
+	if in.Containers != nil {
+		in, out := &in.Containers, &out.Containers
+		*out = new(ContainerSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MetricFreshness != nil {
+		in, out := &in.MetricFreshness, &out.MetricFreshness
+		*out = new(MetricFreshness)
+		**out = **in
+	}
+	if in.PodInitializationPeriod != nil {
+		in, out := &in.PodInitializationPeriod, &out.PodInitializationPeriod
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Metrics != nil {
+		in, out := &in.Metrics, &out.Metrics
+		*out = make([]v2.MetricSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Behavior != nil {
+		in, out := &in.Behavior, &out.Behavior
+		*out = new(v2.HorizontalPodAutoscalerBehavior)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PrometheusQueries != nil {
+		in, out := &in.PrometheusQueries, &out.PrometheusQueries
+		*out = new(PrometheusQueries)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HPAModifierSpec.
@@ -101,6 +176,32 @@ func (in *HPAModifierSpec) DeepCopy() *HPAModifierSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HPAModifierStatus) DeepCopyInto(out *HPAModifierStatus) {
 	*out = *in
+	if in.LastScaledTime != nil {
+		in, out := &in.LastScaledTime, &out.LastScaledTime
+		*out = (*in).DeepCopy()
+	}
+	out.ExcludedPods = in.ExcludedPods
+	if in.NextPreWarm != nil {
+		in, out := &in.NextPreWarm, &out.NextPreWarm
+		*out = new(PreWarmPlan)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodStartupLatency != nil {
+		in, out := &in.PodStartupLatency, &out.PodStartupLatency
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.LastSampleTime != nil {
+		in, out := &in.LastSampleTime, &out.LastSampleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HPAModifierStatus.
@@ -112,3 +213,65 @@ func (in *HPAModifierStatus) DeepCopy() *HPAModifierStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetricFreshness) DeepCopyInto(out *MetricFreshness) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricFreshness.
+func (in *MetricFreshness) DeepCopy() *MetricFreshness {
+	if in == nil {
+		return nil
+	}
+	out := new(MetricFreshness)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodExclusions) DeepCopyInto(out *PodExclusions) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodExclusions.
+func (in *PodExclusions) DeepCopy() *PodExclusions {
+	if in == nil {
+		return nil
+	}
+	out := new(PodExclusions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreWarmPlan) DeepCopyInto(out *PreWarmPlan) {
+	*out = *in
+	in.ScaleUpTime.DeepCopyInto(&out.ScaleUpTime)
+	in.PeakTime.DeepCopyInto(&out.PeakTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreWarmPlan.
+func (in *PreWarmPlan) DeepCopy() *PreWarmPlan {
+	if in == nil {
+		return nil
+	}
+	out := new(PreWarmPlan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusQueries) DeepCopyInto(out *PrometheusQueries) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusQueries.
+func (in *PrometheusQueries) DeepCopy() *PrometheusQueries {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusQueries)
+	in.DeepCopyInto(out)
+	return out
+}
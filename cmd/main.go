@@ -18,7 +18,9 @@ package main
 
 import (
 	"flag"
+	"net/http"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
@@ -35,6 +37,10 @@ import (
 
 	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
 	"yemo.info/auto-scaling-system/internal/controller"
+	metrics2 "yemo.info/auto-scaling-system/internal/metrics"
+	"yemo.info/auto-scaling-system/internal/predictor"
+	"yemo.info/auto-scaling-system/internal/prometheus"
+	"yemo.info/auto-scaling-system/internal/scaler"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -54,11 +60,47 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var historyStore string
+	var historyNamespace string
+	var historyDir string
+	var historySyncInterval time.Duration
+	var prometheusURL string
+	var historyBackfill bool
+	var prometheusCPUQuery string
+	var prometheusMemoryQuery string
+	var metricsCacheTTL time.Duration
+	predictorOptions := predictor.DefaultOptions()
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&predictorOptions.Timeout, "predictor-timeout", predictorOptions.Timeout,
+		"Timeout of a single request to the prediction service.")
+	flag.IntVar(&predictorOptions.MaxRetries, "predictor-max-retries", predictorOptions.MaxRetries,
+		"Maximum number of retries for a failed prediction request.")
+	flag.IntVar(&predictorOptions.FailureThreshold, "predictor-failure-threshold", predictorOptions.FailureThreshold,
+		"Consecutive failed prediction requests before the circuit breaker opens.")
+	flag.DurationVar(&predictorOptions.OpenDuration, "predictor-circuit-open-duration", predictorOptions.OpenDuration,
+		"How long the circuit breaker stays open before a probe request is allowed.")
+	flag.StringVar(&historyStore, "history-store", "none",
+		"Where to persist learned workload history: none, configmap or file.")
+	flag.StringVar(&historyNamespace, "history-namespace", os.Getenv("POD_NAMESPACE"),
+		"Namespace of the history ConfigMaps when --history-store=configmap. Defaults to $POD_NAMESPACE.")
+	flag.StringVar(&historyDir, "history-dir", "/var/lib/hpamodifier/history",
+		"Directory of the history files when --history-store=file.")
+	flag.DurationVar(&historySyncInterval, "history-sync-interval", time.Minute,
+		"How often the leader saves workload history.")
+	flag.StringVar(&prometheusURL, "prometheus-url", "",
+		"Base URL of a Prometheus-compatible query API, e.g. http://prometheus.monitoring.svc:9090.")
+	flag.BoolVar(&historyBackfill, "history-backfill", false,
+		"Backfill the history of new workloads from --prometheus-url.")
+	flag.StringVar(&prometheusCPUQuery, "prometheus-cpu-query", metrics2.DefaultCPUQuery,
+		"PromQL template returning per-pod CPU usage in cores for HPAModifiers with metricsSource Prometheus.")
+	flag.StringVar(&prometheusMemoryQuery, "prometheus-memory-query", metrics2.DefaultMemoryQuery,
+		"PromQL template returning per-pod memory usage in bytes for HPAModifiers with metricsSource Prometheus.")
+	flag.DurationVar(&metricsCacheTTL, "metrics-cache-ttl", metrics2.DefaultMetricsCacheTTL,
+		"How long pod metrics listed from metrics.k8s.io are shared by all HPAModifiers in a namespace, 0 disables the cache.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -67,10 +109,16 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	// 最近的伸缩决策通过指标服务的调试端点查询
+	decisions := scaler.NewDecisionLog()
+
 	// 创建 manager
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:                 scheme,
-		Metrics:                metricsserver.Options{BindAddress: metricsAddr},
+		Scheme: scheme,
+		Metrics: metricsserver.Options{
+			BindAddress:   metricsAddr,
+			ExtraHandlers: map[string]http.Handler{controller.DecisionDebugPath: decisions},
+		},
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "2363ecff.yemo.info",
@@ -95,17 +143,70 @@ func main() {
 		os.Exit(1)
 	}
 
+	// 选择历史数据的持久化方式
+	var historyPersister scaler.HistoryPersister
+	switch historyStore {
+	case "none":
+	case "configmap":
+		if historyNamespace == "" {
+			setupLog.Error(nil, "--history-namespace or $POD_NAMESPACE is required when --history-store=configmap")
+			os.Exit(1)
+		}
+		historyPersister = &scaler.ConfigMapPersister{Client: kubeClient, Namespace: historyNamespace}
+	case "file":
+		historyPersister = &scaler.FilePersister{Dir: historyDir}
+	default:
+		setupLog.Error(nil, "unknown --history-store", "history-store", historyStore)
+		os.Exit(1)
+	}
+
+	// 配置 Prometheus 后，HPAModifier 可以选择 Prometheus 作为指标源
+	var prometheusClient *prometheus.Client
+	var prometheusMetrics scaler.MetricsSource
+	if prometheusURL != "" {
+		prometheusClient = prometheus.NewClient(prometheusURL, 30*time.Second)
+		prometheusMetrics, err = metrics2.NewPrometheusMetricsClient(prometheusClient, prometheusCPUQuery, prometheusMemoryQuery)
+		if err != nil {
+			setupLog.Error(err, "invalid Prometheus query template")
+			os.Exit(1)
+		}
+	}
+
+	// 从 Prometheus 回填新工作负载的历史数据
+	var historySource scaler.HistorySource
+	if historyBackfill {
+		if prometheusClient == nil {
+			setupLog.Error(nil, "--prometheus-url is required when --history-backfill is set")
+			os.Exit(1)
+		}
+		historySource = &prometheus.HistorySource{Client: prometheusClient}
+	}
+
 	// 创建并设置控制器
 	if err = (&controller.HPAModifierReconciler{
-		Client:        mgr.GetClient(),
-		Scheme:        mgr.GetScheme(),
-		Log:           ctrl.Log.WithName("controllers").WithName("HPAModifier"),
-		KubeClient:    kubeClient,
-		MetricsClient: metricsClient,
+		Client:              mgr.GetClient(),
+		Scheme:              mgr.GetScheme(),
+		Log:                 ctrl.Log.WithName("controllers").WithName("HPAModifier"),
+		KubeClient:          kubeClient,
+		MetricsClient:       metricsClient,
+		MetricsCacheTTL:     metricsCacheTTL,
+		PredictorOptions:    predictorOptions,
+		HistoryPersister:    historyPersister,
+		HistorySyncInterval: historySyncInterval,
+		HistorySource:       historySource,
+		PrometheusMetrics:   prometheusMetrics,
+		Recorder:            mgr.GetEventRecorderFor("hpamodifier-controller"),
+		Decisions:           decisions,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "HPAModifier")
 		os.Exit(1)
 	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&autoscalingv1.HPAModifier{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "HPAModifier")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	// 设置健康检查
@@ -4,16 +4,26 @@ import (
 	"context"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"time"
+	"unicode/utf8"
 	metrics2 "yemo.info/auto-scaling-system/internal/metrics"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/scale"
+	"k8s.io/client-go/tools/record"
 	metrics "k8s.io/metrics/pkg/client/clientset/versioned"
+	customclient "k8s.io/metrics/pkg/client/custom_metrics"
+	externalclient "k8s.io/metrics/pkg/client/external_metrics"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+	"yemo.info/auto-scaling-system/internal/predictor"
 	"yemo.info/auto-scaling-system/internal/scaler"
 )
 
@@ -21,6 +31,12 @@ import (
 const (
 	RequeueInterval = 10 * time.Second                                          // 默认重新调度间隔：10秒
 	PredictorURL    = "http://predictor-service.default.svc.cluster.local:8000" // 预测服务的URL
+	// DecisionDebugPath 指标服务上返回最近伸缩决策的调试端点
+	DecisionDebugPath = "/debug/decisions"
+	// maxEventMessageLength Event 消息的最大长度
+	maxEventMessageLength = 1024
+	// NativeHPAFinalizer Modify 模式的 HPAModifier 删除前先恢复它修改过的原生 HPA，控制器停止期间删除也不会遗漏
+	NativeHPAFinalizer = "autoscaling.yemo.info/restore-native-hpa"
 )
 
 // HPAModifierReconciler 用于调谐 HPAModifier 对象
@@ -31,12 +47,35 @@ type HPAModifierReconciler struct {
 	ScalingMgr    *scaler.ScalingManager
 	KubeClient    kubernetes.Interface
 	MetricsClient metrics.Interface
+	// MetricsCacheTTL 同一命名空间的 HPAModifier 共享 Pod 指标的时间，为 0 时每次调谐都按选择器查询
+	MetricsCacheTTL time.Duration
+	// PredictorOptions 预测服务客户端的超时、重试和熔断配置
+	PredictorOptions predictor.Options
+	// HistoryPersister 可选，设置后 leader 启动时恢复历史数据并每隔 HistorySyncInterval 保存一次
+	HistoryPersister    scaler.HistoryPersister
+	HistorySyncInterval time.Duration
+	// HistorySource 可选，为新的工作负载回填历史数据
+	HistorySource scaler.HistorySource
+	// PrometheusMetrics 可选，spec.metricsSource 为 Prometheus 的 HPAModifier 使用的指标源
+	PrometheusMetrics scaler.MetricsSource
+	// Recorder 可选，为伸缩决策创建 Event
+	Recorder record.EventRecorder
+	// Decisions 可选，保存最近的伸缩决策，与调试端点共享
+	Decisions *scaler.DecisionLog
 }
 
 //+kubebuilder:rbac:groups=autoscaling.yemo.info,resources=hpamodifiers,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=autoscaling.yemo.info,resources=hpamodifiers/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=autoscaling.yemo.info,resources=hpamodifiers/finalizers,verbs=update
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;update
+//+kubebuilder:rbac:groups=*,resources=*/scale,verbs=get;update;patch
+//+kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;update
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;create;update;delete
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
 //+kubebuilder:rbac:groups=metrics.k8s.io,resources=pods,verbs=get;list
+//+kubebuilder:rbac:groups=custom.metrics.k8s.io,resources=*,verbs=get;list
+//+kubebuilder:rbac:groups=external.metrics.k8s.io,resources=*,verbs=get;list
 
 // Reconcile 是控制器调谐的主逻辑
 func (r *HPAModifierReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -46,16 +85,47 @@ func (r *HPAModifierReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	hpaModifier := &autoscalingv1.HPAModifier{}
 	if err := r.Get(ctx, req.NamespacedName, hpaModifier); err != nil {
 		if errors.IsNotFound(err) {
+			// 已删除，恢复修改过的原生 HPA 并清理历史数据，失败时重试
+			if err := r.ScalingMgr.Forget(ctx, req.Namespace, req.Name); err != nil {
+				log.Error(err, "清理失败")
+				return ctrl.Result{}, err
+			}
 			return ctrl.Result{}, nil
 		}
 		log.Error(err, "无法获取 HPAModifier")
 		return ctrl.Result{}, err
 	}
 
-	// 使用伸缩管理器执行伸缩
-	if err := r.ScalingMgr.ScaleWorkload(ctx, hpaModifier); err != nil {
-		log.Error(err, "伸缩失败")
-		return ctrl.Result{}, err
+	// 正在删除，恢复修改过的原生 HPA 后移除 finalizer
+	if !hpaModifier.DeletionTimestamp.IsZero() {
+		if controllerutil.ContainsFinalizer(hpaModifier, NativeHPAFinalizer) {
+			if err := r.ScalingMgr.Forget(ctx, req.Namespace, req.Name); err != nil {
+				log.Error(err, "清理失败")
+				return ctrl.Result{}, err
+			}
+			controllerutil.RemoveFinalizer(hpaModifier, NativeHPAFinalizer)
+			if err := r.Update(ctx, hpaModifier); err != nil {
+				log.Error(err, "移除 finalizer 失败")
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Modify 模式会修改原生 HPA，修改前加上 finalizer
+	if hpaModifier.Spec.NativeHPA == autoscalingv1.NativeHPAModify && controllerutil.AddFinalizer(hpaModifier, NativeHPAFinalizer) {
+		if err := r.Update(ctx, hpaModifier); err != nil {
+			log.Error(err, "添加 finalizer 失败")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// 使用伸缩管理器执行伸缩，失败时条件已记录在状态中
+	previous := r.ScalingMgr.LastDecision(req.Namespace, req.Name)
+	scaleErr := r.ScalingMgr.ScaleWorkload(ctx, hpaModifier)
+	hpaModifier.Status.ObservedGeneration = hpaModifier.Generation
+	if decision := r.ScalingMgr.LastDecision(req.Namespace, req.Name); decision != nil {
+		r.reportDecision(log, hpaModifier, previous, decision)
 	}
 
 	// 更新状态
@@ -64,16 +134,107 @@ func (r *HPAModifierReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
+	if scaleErr != nil {
+		// 目标不支持伸缩时重试无意义，按固定间隔重新检查即可
+		if scaler.IsTargetError(scaleErr) {
+			log.Error(scaleErr, "伸缩目标无效")
+			return ctrl.Result{RequeueAfter: RequeueInterval}, nil
+		}
+		log.Error(scaleErr, "伸缩失败")
+		return ctrl.Result{}, scaleErr
+	}
+
 	return ctrl.Result{RequeueAfter: RequeueInterval}, nil
 }
 
+// reportDecision 以结构化日志记录伸缩决策，修改了副本数或原生 HPA、或者动作与原因与上一次不同时同时创建 Event，
+// 其余决策只记录在日志和 DecisionLog 中
+func (r *HPAModifierReconciler) reportDecision(log logr.Logger, hpaModifier *autoscalingv1.HPAModifier,
+	previous, decision *scaler.ScalingDecision) {
+	values := []interface{}{
+		"action", decision.Action,
+		"currentReplicas", decision.Inputs.CurrentReplicas,
+		"desiredReplicas", decision.DesiredReplicas,
+		"decision", decision,
+	}
+	if !decision.NeedsEvent(previous) {
+		log.V(1).Info("伸缩决策", values...)
+		return
+	}
+	log.Info("伸缩决策", values...)
+
+	if r.Recorder == nil {
+		return
+	}
+	eventType := corev1.EventTypeNormal
+	if decision.Action == scaler.ActionFailed || decision.Action == scaler.ActionSkipped {
+		eventType = corev1.EventTypeWarning
+	}
+	r.Recorder.Event(hpaModifier, eventType, string(decision.Action), truncateMessage(decision.Summary(), maxEventMessageLength))
+}
+
+// truncateMessage 将消息截断到 limit 字节以内，在 UTF-8 字符边界处截断
+func truncateMessage(message string, limit int) string {
+	if len(message) <= limit {
+		return message
+	}
+	cut := limit - len("...")
+	for cut > 0 && !utf8.RuneStart(message[cut]) {
+		cut--
+	}
+	return message[:cut] + "..."
+}
+
 // SetupWithManager 设置控制器与管理器
 func (r *HPAModifierReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	// 创建 MetricsClient 适配器
-	metricsClient := metrics2.NewK8sMetricsClient(r.MetricsClient)
+	// 创建 MetricsClient 适配器，按命名空间缓存指标
+	metricsClient := metrics2.NewCachedMetricsClient(metrics2.NewK8sMetricsClient(r.MetricsClient), r.MetricsCacheTTL)
+
+	// 创建支持任意 /scale 子资源的伸缩客户端
+	scaleClient, err := scale.NewForConfig(mgr.GetConfig(), mgr.GetRESTMapper(),
+		dynamic.LegacyAPIPathResolverFunc, scale.NewDiscoveryScaleKindResolver(r.KubeClient.Discovery()))
+	if err != nil {
+		return err
+	}
 
 	// 初始化伸缩管理器
-	r.ScalingMgr = scaler.NewScalingManager(r.KubeClient, metricsClient, PredictorURL)
+	r.ScalingMgr = scaler.NewScalingManager(r.KubeClient, scaleClient, mgr.GetRESTMapper(), metricsClient, PredictorURL)
+	r.ScalingMgr.Predictor = predictor.NewClient(r.PredictorOptions)
+	if r.Decisions != nil {
+		r.ScalingMgr.Decisions = r.Decisions
+	}
+
+	r.ScalingMgr.HistorySource = r.HistorySource
+	r.ScalingMgr.PrometheusMetrics = r.PrometheusMetrics
+
+	// 创建 custom.metrics.k8s.io 和 external.metrics.k8s.io 客户端，读取 spec.metrics 中的指标
+	availableAPIs := customclient.NewAvailableAPIsGetter(r.KubeClient.Discovery())
+	r.ScalingMgr.CustomMetrics = customclient.NewForConfig(mgr.GetConfig(), mgr.GetRESTMapper(), availableAPIs)
+	externalMetrics, err := externalclient.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return err
+	}
+	r.ScalingMgr.ExternalMetrics = externalMetrics
+	// 定期刷新 custom metrics API 的首选版本，适配器升级后无需重启控制器
+	if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		customclient.PeriodicallyInvalidate(availableAPIs, 5*time.Minute, ctx.Done())
+		return nil
+	})); err != nil {
+		return err
+	}
+
+	// 持久化历史数据，只在 leader 上运行
+	if r.HistoryPersister != nil {
+		r.ScalingMgr.HistoryPersister = r.HistoryPersister
+		if err := mgr.Add(&scaler.HistorySyncer{
+			Manager:  r.ScalingMgr,
+			Reader:   mgr.GetAPIReader(),
+			Interval: r.HistorySyncInterval,
+			Log:      r.Log.WithName("history"),
+		}); err != nil {
+			return err
+		}
+	}
 
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&autoscalingv1.HPAModifier{}).
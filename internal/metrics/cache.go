@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+// DefaultMetricsCacheTTL 命名空间 Pod 指标的默认缓存时间，与 metrics-server 的默认采集间隔相同
+const DefaultMetricsCacheTTL = 15 * time.Second
+
+// CachedMetricsClient 按命名空间缓存 Pod 指标，同一命名空间的所有 HPAModifier 共享一次 List 的结果。
+// 缓存过期后第一个调用方重新获取，并发的调用方等待同一次请求，而不是各自发起请求
+type CachedMetricsClient struct {
+	client MetricsClient
+	ttl    time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*namespaceMetrics
+}
+
+// namespaceMetrics 一个命名空间的缓存，mu 保证同一时刻只有一个请求在获取该命名空间的指标
+type namespaceMetrics struct {
+	mu        sync.Mutex
+	list      *metricsv1beta1.PodMetricsList
+	fetchedAt time.Time
+}
+
+// NewCachedMetricsClient 创建按命名空间缓存的指标客户端，ttl 不大于 0 时不缓存，直接按选择器查询
+func NewCachedMetricsClient(client MetricsClient, ttl time.Duration) MetricsClient {
+	if ttl <= 0 {
+		return client
+	}
+	return &CachedMetricsClient{
+		client:  client,
+		ttl:     ttl,
+		entries: make(map[string]*namespaceMetrics),
+	}
+}
+
+// GetPodMetrics 返回命名空间缓存中标签匹配选择器的 Pod 指标，PodMetrics 的标签与 Pod 相同
+func (c *CachedMetricsClient) GetPodMetrics(ctx context.Context, namespace string, selector labels.Selector) (*metricsv1beta1.PodMetricsList, error) {
+	list, err := c.namespaceMetrics(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	filtered := &metricsv1beta1.PodMetricsList{TypeMeta: list.TypeMeta, ListMeta: list.ListMeta}
+	for _, item := range list.Items {
+		if selector.Matches(labels.Set(item.Labels)) {
+			filtered.Items = append(filtered.Items, item)
+		}
+	}
+	return filtered, nil
+}
+
+// namespaceMetrics 返回命名空间未过期的缓存，过期时重新获取。获取失败不缓存，下次调用时重试
+func (c *CachedMetricsClient) namespaceMetrics(ctx context.Context, namespace string) (*metricsv1beta1.PodMetricsList, error) {
+	c.mu.Lock()
+	entry, exists := c.entries[namespace]
+	if !exists {
+		entry = &namespaceMetrics{}
+		c.entries[namespace] = entry
+	}
+	c.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.list != nil && time.Since(entry.fetchedAt) < c.ttl {
+		return entry.list, nil
+	}
+	list, err := c.client.GetPodMetrics(ctx, namespace, labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	entry.list = list
+	entry.fetchedAt = time.Now()
+	return list, nil
+}
@@ -2,14 +2,16 @@ package metrics
 
 import (
 	"context"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
 	metrics "k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
 // MetricsClient 定义了获取指标的接口
 type MetricsClient interface {
-	GetPodMetrics(namespace string) (*metricsv1beta1.PodMetricsList, error)
+	GetPodMetrics(ctx context.Context, namespace string, selector labels.Selector) (*metricsv1beta1.PodMetricsList, error)
 }
 
 // K8sMetricsClient 实现 MetricsClient 接口
@@ -24,7 +26,9 @@ func NewK8sMetricsClient(client metrics.Interface) MetricsClient {
 	}
 }
 
-// GetPodMetrics 获取指定命名空间的 Pod 指标
-func (c *K8sMetricsClient) GetPodMetrics(namespace string) (*metricsv1beta1.PodMetricsList, error) {
-	return c.client.MetricsV1beta1().PodMetricses(namespace).List(context.Background(), metav1.ListOptions{})
+// GetPodMetrics 获取指定命名空间中匹配选择器的 Pod 指标，过滤在服务端完成
+func (c *K8sMetricsClient) GetPodMetrics(ctx context.Context, namespace string, selector labels.Selector) (*metricsv1beta1.PodMetricsList, error) {
+	return c.client.MetricsV1beta1().PodMetricses(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
 }
@@ -0,0 +1,206 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+	"yemo.info/auto-scaling-system/internal/prometheus"
+	"yemo.info/auto-scaling-system/internal/scaler"
+)
+
+// 默认的 PromQL 查询模板，按 pod 和 container 汇总 cAdvisor 指标。
+// cAdvisor 指标不带 Pod 标签，关联 kube-state-metrics 的 kube_pod_labels 按标签选择器筛选目标 Pod；
+// kube-state-metrics v2 默认不导出 Pod 标签，需要以 --metric-labels-allowlist=pods=[*]（或至少包含选择器用到的标签键）启动，
+// 见仓库根目录的 kube-state-metrics.yaml
+const (
+	// DefaultCPUQuery 每个容器最近 2 分钟的平均 CPU 用量（核）
+	DefaultCPUQuery = `sum by (pod, container) (rate(container_cpu_usage_seconds_total{namespace="{{.Namespace}}",container!="",container!="POD"}[2m])` +
+		` * on (namespace, pod) group_left() max by (namespace, pod) (kube_pod_labels{namespace="{{.Namespace}}",{{.LabelMatchers}}}))`
+	// DefaultMemoryQuery 每个容器的工作集内存（字节）
+	DefaultMemoryQuery = `sum by (pod, container) (container_memory_working_set_bytes{namespace="{{.Namespace}}",container!="",container!="POD"}` +
+		` * on (namespace, pod) group_left() max by (namespace, pod) (kube_pod_labels{namespace="{{.Namespace}}",{{.LabelMatchers}}}))`
+)
+
+// QueryTemplateData PromQL 模板可引用的变量，值已按 PromQL 双引号字符串转义，可直接写在引号内
+type QueryTemplateData struct {
+	// Namespace 目标工作负载的命名空间
+	Namespace string
+	// Workload 目标工作负载的名称
+	Workload string
+	// Kind 目标工作负载的类型
+	Kind string
+	// Selector 目标 Pod 的标签选择器，如 app=nginx
+	Selector string
+	// LabelMatchers 由 Selector 转换的 kube-state-metrics 标签匹配器，如 label_app="nginx"，用于关联 kube_pod_labels
+	LabelMatchers string
+}
+
+// PrometheusMetricsClient 执行模板化的 PromQL 查询获取 Pod 用量，可以使用平滑后的速率
+// 或 metrics-server 不提供的指标
+type PrometheusMetricsClient struct {
+	client      *prometheus.Client
+	cpuQuery    *template.Template
+	memoryQuery *template.Template
+}
+
+var _ scaler.MetricsSource = &PrometheusMetricsClient{}
+
+// NewPrometheusMetricsClient 创建 Prometheus 指标源，cpuQuery 和 memoryQuery 为默认查询模板
+func NewPrometheusMetricsClient(client *prometheus.Client, cpuQuery, memoryQuery string) (*PrometheusMetricsClient, error) {
+	cpu, err := parseQuery("cpu", cpuQuery)
+	if err != nil {
+		return nil, err
+	}
+	memory, err := parseQuery("memory", memoryQuery)
+	if err != nil {
+		return nil, err
+	}
+	return &PrometheusMetricsClient{client: client, cpuQuery: cpu, memoryQuery: memory}, nil
+}
+
+// ForWorkload 返回查询 HPAModifier 目标的 MetricsClient，spec.prometheusQueries 覆盖默认查询模板
+func (c *PrometheusMetricsClient) ForWorkload(hpa *autoscalingv1.HPAModifier) (scaler.MetricsClient, error) {
+	client := &workloadPrometheusClient{
+		client:      c.client,
+		cpuQuery:    c.cpuQuery,
+		memoryQuery: c.memoryQuery,
+		kind:        hpa.Spec.TargetRef.Kind,
+		workload:    hpa.Spec.TargetRef.Name,
+	}
+
+	var err error
+	if queries := hpa.Spec.PrometheusQueries; queries != nil {
+		if queries.CPU != "" {
+			if client.cpuQuery, err = parseQuery("cpu", queries.CPU); err != nil {
+				return nil, err
+			}
+		}
+		if queries.Memory != "" {
+			if client.memoryQuery, err = parseQuery("memory", queries.Memory); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return client, nil
+}
+
+// parseQuery 解析 PromQL 查询模板
+func parseQuery(name, query string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s query template: %v", name, err)
+	}
+	return tmpl, nil
+}
+
+// workloadPrometheusClient 查询一个工作负载的 Pod 用量
+type workloadPrometheusClient struct {
+	client      *prometheus.Client
+	cpuQuery    *template.Template
+	memoryQuery *template.Template
+	kind        string
+	workload    string
+}
+
+// GetPodMetrics 执行 CPU 和内存查询，按 pod 和 container 标签组装成与 metrics.k8s.io 相同的 PodMetricsList
+func (c *workloadPrometheusClient) GetPodMetrics(ctx context.Context, namespace string, selector labels.Selector) (*metricsv1beta1.PodMetricsList, error) {
+	matchers, err := prometheus.LabelMatchers(selector)
+	if err != nil {
+		return nil, err
+	}
+	data := QueryTemplateData{
+		Namespace:     prometheus.Escape(namespace),
+		Workload:      prometheus.Escape(c.workload),
+		Kind:          prometheus.Escape(c.kind),
+		Selector:      prometheus.Escape(selector.String()),
+		LabelMatchers: matchers,
+	}
+
+	now := time.Now()
+	cpu, err := c.query(ctx, c.cpuQuery, data, now)
+	if err != nil {
+		return nil, err
+	}
+	memory, err := c.query(ctx, c.memoryQuery, data, now)
+	if err != nil {
+		return nil, err
+	}
+	// 两个查询都没有结果时多半是 kube_pod_labels 缺少选择器用到的标签，返回错误而不是当作没有 Pod
+	if len(cpu) == 0 && len(memory) == 0 {
+		return nil, fmt.Errorf("cpu and memory queries returned no series for pods matching %q in %s, "+
+			"check that kube-state-metrics exports the pod labels with --metric-labels-allowlist", selector.String(), namespace)
+	}
+
+	pods := make(map[string]*metricsv1beta1.PodMetrics)
+	add := func(series []prometheus.Series, resourceName corev1.ResourceName, quantity func(float64) resource.Quantity) {
+		for _, s := range series {
+			podName := s.Labels["pod"]
+			if podName == "" || len(s.Points) == 0 {
+				continue
+			}
+			pod, exists := pods[podName]
+			if !exists {
+				pod = &metricsv1beta1.PodMetrics{ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: namespace}}
+				pods[podName] = pod
+			}
+			point := s.Points[len(s.Points)-1]
+			if pod.Timestamp.Time.Before(point.Timestamp) {
+				pod.Timestamp = metav1.NewTime(point.Timestamp)
+			}
+			container := containerMetrics(pod, s.Labels["container"])
+			container.Usage[resourceName] = quantity(point.Value)
+		}
+	}
+	add(cpu, corev1.ResourceCPU, func(cores float64) resource.Quantity {
+		return *resource.NewMilliQuantity(int64(cores*1000), resource.DecimalSI)
+	})
+	add(memory, corev1.ResourceMemory, func(bytes float64) resource.Quantity {
+		return *resource.NewQuantity(int64(bytes), resource.BinarySI)
+	})
+
+	names := make([]string, 0, len(pods))
+	for name := range pods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := &metricsv1beta1.PodMetricsList{}
+	for _, name := range names {
+		list.Items = append(list.Items, *pods[name])
+	}
+	return list, nil
+}
+
+// query 渲染查询模板并执行即时查询
+func (c *workloadPrometheusClient) query(ctx context.Context, tmpl *template.Template, data QueryTemplateData, ts time.Time) ([]prometheus.Series, error) {
+	var query strings.Builder
+	if err := tmpl.Execute(&query, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s query: %v", tmpl.Name(), err)
+	}
+	series, err := c.client.Query(ctx, query.String(), ts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s usage: %v", tmpl.Name(), err)
+	}
+	return series, nil
+}
+
+// containerMetrics 返回 Pod 中指定名称的容器，不存在时添加
+func containerMetrics(pod *metricsv1beta1.PodMetrics, name string) *metricsv1beta1.ContainerMetrics {
+	for i := range pod.Containers {
+		if pod.Containers[i].Name == name {
+			return &pod.Containers[i]
+		}
+	}
+	pod.Containers = append(pod.Containers, metricsv1beta1.ContainerMetrics{Name: name, Usage: corev1.ResourceList{}})
+	return &pod.Containers[len(pod.Containers)-1]
+}
@@ -0,0 +1,340 @@
+package predictor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 预测请求失败的原因，会作为 HPAModifier 状态条件的 Reason
+const (
+	// ReasonUnreachable 网络错误，无法连接预测服务
+	ReasonUnreachable = "PredictorUnreachable"
+	// ReasonTimeout 请求超时
+	ReasonTimeout = "PredictorTimeout"
+	// ReasonBadStatus 预测服务返回非 2xx 状态码
+	ReasonBadStatus = "PredictorBadStatus"
+	// ReasonInvalidResponse 响应无法解析
+	ReasonInvalidResponse = "PredictorInvalidResponse"
+	// ReasonCircuitOpen 熔断器打开，请求未发出
+	ReasonCircuitOpen = "PredictorCircuitOpen"
+	// ReasonCanceled 调用方的 context 已结束，不计入熔断器的失败次数
+	ReasonCanceled = "PredictorRequestCanceled"
+)
+
+// PredictionResponse 定义预测服务的响应结构
+type PredictionResponse struct {
+	Values    []float64          `json:"values"`            // 预测值数组
+	Features  map[string]float64 `json:"features"`          // 特征值
+	Timestamp string             `json:"timestamp"`         // 预测时间戳
+	Horizon   int32              `json:"horizon,omitempty"` // 预测服务实际使用的时间窗口（秒），可选
+}
+
+// Request 定义一次预测请求
+type Request struct {
+	Target    string        // 预测指标，如 cpu、memory
+	Namespace string        // 工作负载所在命名空间
+	Workload  string        // 工作负载名称
+	Kind      string        // 工作负载类型，可为空
+	Horizon   time.Duration // 预测时间窗口
+	Step      time.Duration // 预测序列步长
+}
+
+// Error 预测请求失败时返回的错误
+type Error struct {
+	Reason     string
+	Endpoint   string
+	StatusCode int
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("prediction service %s returned %d: %v", e.Endpoint, e.StatusCode, e.Err)
+	}
+	return fmt.Sprintf("prediction service %s: %v", e.Endpoint, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ReasonOf 返回预测错误的原因，不是预测错误时返回空字符串
+func ReasonOf(err error) string {
+	var predictorErr *Error
+	if errors.As(err, &predictorErr) {
+		return predictorErr.Reason
+	}
+	return ""
+}
+
+// Options 预测客户端配置
+type Options struct {
+	// Timeout 单次请求超时时间
+	Timeout time.Duration
+	// MaxRetries 失败后的最大重试次数
+	MaxRetries int
+	// RetryBackoff 首次重试前的等待时间，之后指数增长并加入随机抖动
+	RetryBackoff time.Duration
+	// MaxRetryBackoff 重试等待时间的上限，不小于 RetryBackoff
+	MaxRetryBackoff time.Duration
+	// FailureThreshold 连续失败多少次后打开熔断器
+	FailureThreshold int
+	// OpenDuration 熔断器打开后多久允许一次试探请求
+	OpenDuration time.Duration
+}
+
+// DefaultOptions 返回默认的客户端配置
+func DefaultOptions() Options {
+	return Options{
+		Timeout:          3 * time.Second,
+		MaxRetries:       2,
+		RetryBackoff:     200 * time.Millisecond,
+		MaxRetryBackoff:  5 * time.Second,
+		FailureThreshold: 5,
+		OpenDuration:     30 * time.Second,
+	}
+}
+
+// Client 访问外部预测服务的客户端，每个服务地址有独立的熔断器
+type Client struct {
+	httpClient *http.Client
+	options    Options
+
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+// NewClient 创建预测客户端，未设置的配置项使用默认值
+func NewClient(options Options) *Client {
+	defaults := DefaultOptions()
+	if options.Timeout <= 0 {
+		options.Timeout = defaults.Timeout
+	}
+	if options.MaxRetries < 0 {
+		options.MaxRetries = 0
+	}
+	if options.RetryBackoff <= 0 {
+		options.RetryBackoff = defaults.RetryBackoff
+	}
+	if options.MaxRetryBackoff <= 0 {
+		options.MaxRetryBackoff = defaults.MaxRetryBackoff
+	}
+	if options.MaxRetryBackoff < options.RetryBackoff {
+		options.MaxRetryBackoff = options.RetryBackoff
+	}
+	if options.FailureThreshold <= 0 {
+		options.FailureThreshold = defaults.FailureThreshold
+	}
+	if options.OpenDuration <= 0 {
+		options.OpenDuration = defaults.OpenDuration
+	}
+	return &Client{
+		httpClient: &http.Client{},
+		options:    options,
+		breakers:   make(map[string]*circuitBreaker),
+	}
+}
+
+// Predict 向 endpoint 请求预测结果，对可重试的错误进行有限次数的重试
+func (c *Client) Predict(ctx context.Context, endpoint string, req Request) (*PredictionResponse, error) {
+	breaker := c.breaker(endpoint)
+	if !breaker.allow() {
+		return nil, &Error{
+			Reason:   ReasonCircuitOpen,
+			Endpoint: endpoint,
+			Err:      fmt.Errorf("circuit breaker open after %d consecutive failures", c.options.FailureThreshold),
+		}
+	}
+
+	var lastErr *Error
+	for attempt := 0; attempt <= c.options.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if err := c.wait(ctx, attempt); err != nil {
+				break
+			}
+		}
+
+		result, err := c.do(ctx, endpoint, req)
+		if err == nil {
+			breaker.recordSuccess()
+			return result, nil
+		}
+		lastErr = err
+		if !retryable(err) {
+			break
+		}
+	}
+
+	// 调用方取消或超时不代表预测服务故障，不计入失败次数，只释放可能占用的试探名额
+	if ctx.Err() != nil {
+		breaker.release()
+		return nil, &Error{Reason: ReasonCanceled, Endpoint: endpoint, Err: ctx.Err()}
+	}
+	breaker.recordFailure()
+	return nil, lastErr
+}
+
+// do 发送一次预测请求
+func (c *Client) do(ctx context.Context, endpoint string, req Request) (*PredictionResponse, *Error) {
+	query := url.Values{}
+	query.Set("target", req.Target)
+	query.Set("namespace", req.Namespace)
+	query.Set("workload", req.Workload)
+	if req.Kind != "" {
+		query.Set("kind", req.Kind)
+	}
+	query.Set("horizon", strconv.FormatInt(int64(req.Horizon/time.Second), 10))
+	query.Set("step", strconv.FormatInt(int64(req.Step/time.Second), 10))
+
+	ctx, cancel := context.WithTimeout(ctx, c.options.Timeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/predict?%s", endpoint, query.Encode()), nil)
+	if err != nil {
+		return nil, &Error{Reason: ReasonUnreachable, Endpoint: endpoint, Err: err}
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, &Error{Reason: ReasonTimeout, Endpoint: endpoint, Err: fmt.Errorf("request timed out after %s", c.options.Timeout)}
+		}
+		return nil, &Error{Reason: ReasonUnreachable, Endpoint: endpoint, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// 只保留响应体开头的一小段，避免把整页 HTML 写进状态
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return nil, &Error{
+			Reason:     ReasonBadStatus,
+			Endpoint:   endpoint,
+			StatusCode: resp.StatusCode,
+			Err:        fmt.Errorf("%q", string(body)),
+		}
+	}
+
+	var result PredictionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, &Error{Reason: ReasonInvalidResponse, Endpoint: endpoint, Err: fmt.Errorf("failed to decode response: %v", err)}
+	}
+	return &result, nil
+}
+
+// wait 按指数退避加随机抖动等待下一次重试，ctx 结束时提前返回
+func (c *Client) wait(ctx context.Context, attempt int) error {
+	backoff := retryBackoff(c.options.RetryBackoff, c.options.MaxRetryBackoff, attempt)
+	jittered := backoff/2 + time.Duration(rand.Int63n(int64(backoff)))
+
+	timer := time.NewTimer(jittered)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryBackoff 返回第 attempt 次重试的退避时间 base * 2^(attempt-1)，不超过 max。
+// 移位溢出时结果不再大于 base，同样取 max
+func retryBackoff(base, max time.Duration, attempt int) time.Duration {
+	shift := attempt - 1
+	if shift >= 63 {
+		return max
+	}
+	backoff := base << shift
+	if backoff < base || backoff>>shift != base || backoff > max {
+		return max
+	}
+	return backoff
+}
+
+// retryable 判断错误是否值得重试：网络错误、超时、429 和 5xx
+func retryable(err *Error) bool {
+	switch err.Reason {
+	case ReasonUnreachable, ReasonTimeout:
+		return true
+	case ReasonBadStatus:
+		return err.StatusCode == http.StatusTooManyRequests || err.StatusCode >= 500
+	default:
+		return false
+	}
+}
+
+// breaker 返回 endpoint 对应的熔断器
+func (c *Client) breaker(endpoint string) *circuitBreaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	breaker, exists := c.breakers[endpoint]
+	if !exists {
+		breaker = &circuitBreaker{
+			failureThreshold: c.options.FailureThreshold,
+			openDuration:     c.options.OpenDuration,
+		}
+		c.breakers[endpoint] = breaker
+	}
+	return breaker
+}
+
+// circuitBreaker 连续失败达到阈值后打开，打开期间拒绝请求，
+// 超过 openDuration 后放行一次试探请求，成功则关闭
+type circuitBreaker struct {
+	mu               sync.Mutex
+	failureThreshold int
+	openDuration     time.Duration
+	failures         int
+	openedAt         time.Time
+	probing          bool
+}
+
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.failureThreshold {
+		return true
+	}
+	if b.probing || time.Since(b.openedAt) < b.openDuration {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *circuitBreaker) recordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.probing = false
+}
+
+// release 请求未得出结论时释放试探名额，不改变失败次数
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+func (b *circuitBreaker) recordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.failures >= b.failureThreshold {
+		b.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,162 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Point 时间序列中的一个点
+type Point struct {
+	Timestamp time.Time
+	Value     float64
+}
+
+// Series 一条带标签的时间序列
+type Series struct {
+	Labels map[string]string
+	Points []Point
+}
+
+// Client 访问 Prometheus 兼容的 HTTP 查询 API
+type Client struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+// NewClient 创建查询客户端，timeout 为单次请求的超时时间
+func NewClient(url string, timeout time.Duration) *Client {
+	return &Client{
+		URL:        url,
+		HTTPClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// apiResponse /api/v1/query 和 /api/v1/query_range 的响应
+type apiResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Metric map[string]string `json:"metric"`
+			Value  []interface{}     `json:"value"`
+			Values [][]interface{}   `json:"values"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// Query 执行即时查询，返回 vector 结果，每条序列只有一个点
+func (c *Client) Query(ctx context.Context, query string, ts time.Time) ([]Series, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("time", formatTime(ts))
+
+	resp, err := c.do(ctx, "/api/v1/query", params)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Data.ResultType != "vector" {
+		return nil, fmt.Errorf("unexpected result type %q for instant query", resp.Data.ResultType)
+	}
+
+	series := make([]Series, 0, len(resp.Data.Result))
+	for _, result := range resp.Data.Result {
+		point, err := parsePoint(result.Value)
+		if err != nil {
+			return nil, err
+		}
+		series = append(series, Series{Labels: result.Metric, Points: []Point{point}})
+	}
+	return series, nil
+}
+
+// QueryRange 执行区间查询，返回 matrix 结果
+func (c *Client) QueryRange(ctx context.Context, query string, start, end time.Time, step time.Duration) ([]Series, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", formatTime(start))
+	params.Set("end", formatTime(end))
+	params.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+
+	resp, err := c.do(ctx, "/api/v1/query_range", params)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Data.ResultType != "matrix" {
+		return nil, fmt.Errorf("unexpected result type %q for range query", resp.Data.ResultType)
+	}
+
+	series := make([]Series, 0, len(resp.Data.Result))
+	for _, result := range resp.Data.Result {
+		points := make([]Point, 0, len(result.Values))
+		for _, value := range result.Values {
+			point, err := parsePoint(value)
+			if err != nil {
+				return nil, err
+			}
+			points = append(points, point)
+		}
+		series = append(series, Series{Labels: result.Metric, Points: points})
+	}
+	return series, nil
+}
+
+// do 发送查询请求并检查响应状态
+func (c *Client) do(ctx context.Context, path string, params url.Values) (*apiResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	httpResp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query prometheus %s: %v", c.URL, err)
+	}
+	defer httpResp.Body.Close()
+
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prometheus response: %v", err)
+	}
+	var resp apiResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("prometheus %s returned %d with an invalid body: %v", c.URL, httpResp.StatusCode, err)
+	}
+	if resp.Status != "success" {
+		return nil, fmt.Errorf("prometheus query failed (%s): %s", resp.ErrorType, resp.Error)
+	}
+	return &resp, nil
+}
+
+// parsePoint 解析 [<unix 时间戳>, "<值>"] 格式的点
+func parsePoint(value []interface{}) (Point, error) {
+	if len(value) != 2 {
+		return Point{}, fmt.Errorf("malformed sample %v", value)
+	}
+	timestamp, ok := value[0].(float64)
+	if !ok {
+		return Point{}, fmt.Errorf("malformed sample timestamp %v", value[0])
+	}
+	text, ok := value[1].(string)
+	if !ok {
+		return Point{}, fmt.Errorf("malformed sample value %v", value[1])
+	}
+	v, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return Point{}, fmt.Errorf("malformed sample value %q: %v", text, err)
+	}
+	sec := int64(timestamp)
+	nsec := int64((timestamp - float64(sec)) * 1e9)
+	return Point{Timestamp: time.Unix(sec, nsec), Value: v}, nil
+}
+
+// formatTime 按 Prometheus API 的要求格式化为 Unix 秒
+func formatTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixNano())/1e9, 'f', -1, 64)
+}
@@ -0,0 +1,68 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+	"yemo.info/auto-scaling-system/internal/scaler"
+)
+
+// 按 Pod 汇总容器用量后取平均，与 metrics-server 路径下 CollectMetrics 的口径一致。
+// cAdvisor 指标不带 Pod 标签，关联 kube-state-metrics 的 kube_pod_labels 按标签选择器筛选目标 Pod；
+// kube-state-metrics v2 默认只导出 name/namespace 等标签，需要以 --metric-labels-allowlist=pods=[*]
+// （或至少包含选择器用到的标签键）启动，否则查询没有结果，见仓库根目录的 kube-state-metrics.yaml。
+// 命名空间用 %q 按 PromQL 字符串的规则转义
+const (
+	cpuHistoryQuery = `avg(sum by (pod) (rate(container_cpu_usage_seconds_total{namespace=%q,container!="",container!="POD"}[5m])` +
+		` * on (namespace, pod) group_left() max by (namespace, pod) (kube_pod_labels{namespace=%q,%s})))`
+	// 内存换算为 GiB
+	memoryHistoryQuery = `avg(sum by (pod) (container_memory_working_set_bytes{namespace=%q,container!="",container!="POD"}` +
+		` * on (namespace, pod) group_left() max by (namespace, pod) (kube_pod_labels{namespace=%q,%s}))) / 1073741824`
+)
+
+// HistorySource 从 Prometheus 读取 cAdvisor 指标，为新的工作负载回填历史数据
+type HistorySource struct {
+	Client *Client
+}
+
+var _ scaler.HistorySource = &HistorySource{}
+
+// WorkloadHistory 返回 selector 匹配的 Pod 在 [start, end] 内每个 Pod 的平均 CPU（核）和内存（GiB）用量
+func (h *HistorySource) WorkloadHistory(ctx context.Context, hpa *autoscalingv1.HPAModifier, selector labels.Selector,
+	start, end time.Time, step time.Duration) ([]scaler.Sample, []scaler.Sample, error) {
+	matchers, err := LabelMatchers(selector)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cpu, err := h.queryAverage(ctx, fmt.Sprintf(cpuHistoryQuery, hpa.Namespace, hpa.Namespace, matchers), start, end, step)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query cpu history: %v", err)
+	}
+	memory, err := h.queryAverage(ctx, fmt.Sprintf(memoryHistoryQuery, hpa.Namespace, hpa.Namespace, matchers), start, end, step)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query memory history: %v", err)
+	}
+	return cpu, memory, nil
+}
+
+// queryAverage 执行返回单条序列的区间查询
+func (h *HistorySource) queryAverage(ctx context.Context, query string, start, end time.Time, step time.Duration) ([]scaler.Sample, error) {
+	series, err := h.Client.QueryRange(ctx, query, start, end, step)
+	if err != nil {
+		return nil, err
+	}
+	if len(series) == 0 {
+		return nil, nil
+	}
+
+	samples := make([]scaler.Sample, len(series[0].Points))
+	for i, point := range series[0].Points {
+		samples[i] = scaler.Sample{Timestamp: point.Timestamp, Value: point.Value}
+	}
+	return samples, nil
+}
@@ -0,0 +1,49 @@
+package prometheus
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// LabelMatchers 将标签选择器转换为 kube_pod_labels 的 PromQL 标签匹配器，如 label_app="nginx"
+func LabelMatchers(selector labels.Selector) (string, error) {
+	requirements, _ := selector.Requirements()
+	matchers := make([]string, 0, len(requirements))
+	for _, requirement := range requirements {
+		name := "label_" + invalidLabelChars.ReplaceAllString(requirement.Key(), "_")
+		values := requirement.Values().List()
+		quoted := make([]string, len(values))
+		for i, value := range values {
+			quoted[i] = regexp.QuoteMeta(value)
+		}
+		switch requirement.Operator() {
+		case selection.Equals, selection.DoubleEquals:
+			matchers = append(matchers, fmt.Sprintf(`%s="%s"`, name, Escape(values[0])))
+		case selection.NotEquals:
+			matchers = append(matchers, fmt.Sprintf(`%s!="%s"`, name, Escape(values[0])))
+		case selection.In:
+			matchers = append(matchers, fmt.Sprintf(`%s=~"%s"`, name, Escape(strings.Join(quoted, "|"))))
+		case selection.NotIn:
+			matchers = append(matchers, fmt.Sprintf(`%s!~"%s"`, name, Escape(strings.Join(quoted, "|"))))
+		case selection.Exists:
+			matchers = append(matchers, fmt.Sprintf(`%s!=""`, name))
+		case selection.DoesNotExist:
+			matchers = append(matchers, fmt.Sprintf(`%s=""`, name))
+		default:
+			return "", fmt.Errorf("selector operator %q cannot be expressed as a PromQL label matcher", requirement.Operator())
+		}
+	}
+	return strings.Join(matchers, ","), nil
+}
+
+// invalidLabelChars kube-state-metrics 将标签名中的这些字符替换为下划线
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// Escape 按 PromQL 双引号字符串的规则转义
+func Escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+}
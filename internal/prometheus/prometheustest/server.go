@@ -0,0 +1,123 @@
+// Package prometheustest 提供模拟 Prometheus 查询 API 的 httptest 服务，用于测试
+package prometheustest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"yemo.info/auto-scaling-system/internal/prometheus"
+)
+
+// Query 服务收到的一次查询
+type Query struct {
+	Path  string
+	Query string
+	Time  time.Time // 即时查询的时间
+	Start time.Time
+	End   time.Time
+	Step  time.Duration
+}
+
+// Responder 根据查询返回结果序列，即时查询只使用每条序列的最后一个点；返回错误时服务响应 status=error
+type Responder func(query Query) ([]prometheus.Series, error)
+
+// Server 模拟的 Prometheus 服务，记录收到的所有查询
+type Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	queries []Query
+}
+
+// NewServer 启动模拟服务，调用方负责 Close
+func NewServer(respond Responder) *Server {
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := Query{
+			Path:  r.URL.Path,
+			Query: r.FormValue("query"),
+			Time:  parseTime(r.FormValue("time")),
+			Start: parseTime(r.FormValue("start")),
+			End:   parseTime(r.FormValue("end")),
+		}
+		if step, err := strconv.ParseFloat(r.FormValue("step"), 64); err == nil {
+			query.Step = time.Duration(step * float64(time.Second))
+		}
+		s.mu.Lock()
+		s.queries = append(s.queries, query)
+		s.mu.Unlock()
+
+		series, err := respond(query)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"status": "error", "errorType": "bad_data", "error": err.Error()})
+			return
+		}
+		if query.Path == "/api/v1/query" {
+			_ = json.NewEncoder(w).Encode(vectorResponse(series))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(matrixResponse(series))
+	}))
+	return s
+}
+
+// Queries 返回收到的查询
+func (s *Server) Queries() []Query {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Query(nil), s.queries...)
+}
+
+// matrixResponse 按区间查询的格式编码结果
+func matrixResponse(series []prometheus.Series) map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(series))
+	for _, s := range series {
+		values := make([][]interface{}, 0, len(s.Points))
+		for _, point := range s.Points {
+			values = append(values, []interface{}{
+				float64(point.Timestamp.UnixNano()) / 1e9,
+				strconv.FormatFloat(point.Value, 'f', -1, 64),
+			})
+		}
+		result = append(result, map[string]interface{}{"metric": s.Labels, "values": values})
+	}
+	return map[string]interface{}{
+		"status": "success",
+		"data":   map[string]interface{}{"resultType": "matrix", "result": result},
+	}
+}
+
+// vectorResponse 按即时查询的格式编码结果
+func vectorResponse(series []prometheus.Series) map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(series))
+	for _, s := range series {
+		if len(s.Points) == 0 {
+			continue
+		}
+		point := s.Points[len(s.Points)-1]
+		result = append(result, map[string]interface{}{
+			"metric": s.Labels,
+			"value": []interface{}{
+				float64(point.Timestamp.UnixNano()) / 1e9,
+				strconv.FormatFloat(point.Value, 'f', -1, 64),
+			},
+		})
+	}
+	return map[string]interface{}{
+		"status": "success",
+		"data":   map[string]interface{}{"resultType": "vector", "result": result},
+	}
+}
+
+func parseTime(value string) time.Time {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(seconds*1e9))
+}
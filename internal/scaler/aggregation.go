@@ -0,0 +1,84 @@
+package scaler
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+)
+
+// aggregationFunction 返回 HPAModifier 跨 Pod 汇总用量的方式，未设置时为 Mean
+func aggregationFunction(hpa *autoscalingv1.HPAModifier) autoscalingv1.AggregationFunction {
+	if hpa.Spec.Aggregation == "" {
+		return autoscalingv1.AggregationMean
+	}
+	return hpa.Spec.Aggregation
+}
+
+// includeContainer 判断容器是否按 spec.containers 计入用量
+func includeContainer(hpa *autoscalingv1.HPAModifier, name string) bool {
+	selector := hpa.Spec.Containers
+	if selector == nil {
+		return true
+	}
+	for _, excluded := range selector.Exclude {
+		if name == excluded {
+			return false
+		}
+	}
+	if len(selector.Include) == 0 {
+		return true
+	}
+	for _, included := range selector.Include {
+		if name == included {
+			return true
+		}
+	}
+	return false
+}
+
+// noSelectedContainersError 目标 Pod 中没有容器匹配 spec.containers 时返回的错误
+func noSelectedContainersError(pods int) error {
+	return &TargetError{
+		Reason:  ReasonNoSelectedContainers,
+		Message: fmt.Sprintf("none of the containers of the %d target pods match spec.containers", pods),
+	}
+}
+
+// aggregate 按汇总方式计算每个 Pod 的值，values 为空时返回 0
+func aggregate(fn autoscalingv1.AggregationFunction, values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	switch fn {
+	case autoscalingv1.AggregationMax:
+		peak := values[0]
+		for _, value := range values[1:] {
+			peak = math.Max(peak, value)
+		}
+		return peak
+	case autoscalingv1.AggregationMedian:
+		return percentile(values, 0.5)
+	case autoscalingv1.AggregationP90:
+		return percentile(values, 0.9)
+	case autoscalingv1.AggregationP95:
+		return percentile(values, 0.95)
+	default:
+		sum := 0.0
+		for _, value := range values {
+			sum += value
+		}
+		return sum / float64(len(values))
+	}
+}
+
+// percentile 在相邻两个排名之间线性插值计算百分位数，不修改 values
+func percentile(values []float64, p float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := p * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
@@ -0,0 +1,59 @@
+package scaler
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+)
+
+// HistorySource 为还没有历史数据的工作负载提供回填数据，如 Prometheus
+type HistorySource interface {
+	// WorkloadHistory 返回 selector 匹配的 Pod 在 [start, end] 内按 step 采样的每 Pod 平均 CPU（核）和内存（GiB）用量
+	WorkloadHistory(ctx context.Context, hpa *autoscalingv1.HPAModifier, selector labels.Selector,
+		start, end time.Time, step time.Duration) (cpu, memory []Sample, err error)
+}
+
+// backfillHistory 工作负载第一次调谐且没有历史数据时，从 HistorySource 回填一个历史窗口的数据。
+// 每个工作负载只尝试一次，失败时继续按实时数据积累历史。
+// HistorySource 返回绝对用量，Utilization 模式下历史中是占 requests 的比例，不回填
+func (s *ScalingManager) backfillHistory(ctx context.Context, hpa *autoscalingv1.HPAModifier, selector labels.Selector) {
+	if s.HistorySource == nil || usesUtilization(hpa) {
+		return
+	}
+
+	key := workloadKey(hpa)
+	s.mu.Lock()
+	if s.backfilled == nil {
+		s.backfilled = make(map[string]bool)
+	}
+	attempted := s.backfilled[key]
+	s.backfilled[key] = true
+	s.mu.Unlock()
+	if attempted {
+		return
+	}
+
+	// 已从持久化快照恢复的工作负载不需要回填
+	store := s.strategyFactory.Store()
+	if len(store.Samples(key)) > 1 {
+		return
+	}
+
+	end := time.Now()
+	cpu, memory, err := s.HistorySource.WorkloadHistory(ctx, hpa, selector, end.Add(-store.Window()), end, store.Interval())
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to backfill workload history", "workload", key)
+		return
+	}
+	for _, sample := range cpu {
+		store.Add(key, sample.Timestamp, sample.Value)
+	}
+	for _, sample := range memory {
+		store.Add(historyKey(hpa, "memory"), sample.Timestamp, sample.Value)
+	}
+	log.FromContext(ctx).Info("backfilled workload history", "workload", key, "cpuSamples", len(cpu), "memorySamples", len(memory))
+}
@@ -0,0 +1,340 @@
+package scaler
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+)
+
+// 与 autoscaling/v2 HPA 相同的默认规则
+const (
+	defaultScaleDownStabilizationSeconds = 300
+	defaultScalingPolicyPeriodSeconds    = 15
+)
+
+// DefaultScaleUpRules 返回未配置 scaleUp 时使用的规则：每 15 秒最多翻倍或增加 4 个副本，取较大者
+func DefaultScaleUpRules() *autoscalingv2.HPAScalingRules {
+	selectPolicy := autoscalingv2.MaxChangePolicySelect
+	stabilizationWindow := int32(0)
+	return &autoscalingv2.HPAScalingRules{
+		StabilizationWindowSeconds: &stabilizationWindow,
+		SelectPolicy:               &selectPolicy,
+		Policies: []autoscalingv2.HPAScalingPolicy{
+			{Type: autoscalingv2.PodsScalingPolicy, Value: 4, PeriodSeconds: defaultScalingPolicyPeriodSeconds},
+			{Type: autoscalingv2.PercentScalingPolicy, Value: 100, PeriodSeconds: defaultScalingPolicyPeriodSeconds},
+		},
+	}
+}
+
+// DefaultScaleDownRules 返回未配置 scaleDown 时使用的规则：稳定窗口 5 分钟，每 15 秒最多缩容 100%
+func DefaultScaleDownRules() *autoscalingv2.HPAScalingRules {
+	selectPolicy := autoscalingv2.MaxChangePolicySelect
+	stabilizationWindow := int32(defaultScaleDownStabilizationSeconds)
+	return &autoscalingv2.HPAScalingRules{
+		StabilizationWindowSeconds: &stabilizationWindow,
+		SelectPolicy:               &selectPolicy,
+		Policies: []autoscalingv2.HPAScalingPolicy{
+			{Type: autoscalingv2.PercentScalingPolicy, Value: 100, PeriodSeconds: defaultScalingPolicyPeriodSeconds},
+		},
+	}
+}
+
+// scalingRules 返回某个方向生效的规则，未配置的字段使用默认值
+func scalingRules(rules, defaults *autoscalingv2.HPAScalingRules) *autoscalingv2.HPAScalingRules {
+	if rules == nil {
+		return defaults
+	}
+	merged := rules.DeepCopy()
+	if merged.StabilizationWindowSeconds == nil {
+		merged.StabilizationWindowSeconds = defaults.StabilizationWindowSeconds
+	}
+	if merged.SelectPolicy == nil {
+		merged.SelectPolicy = defaults.SelectPolicy
+	}
+	if len(merged.Policies) == 0 {
+		merged.Policies = defaults.Policies
+	}
+	return merged
+}
+
+// timestampedRecommendation 一次调谐给出的期望副本数
+type timestampedRecommendation struct {
+	replicas  int32
+	timestamp time.Time
+}
+
+// scaleEvent 一次实际发生的扩容或缩容，replicaChange 为变化的副本数（取绝对值）
+type scaleEvent struct {
+	replicaChange int32
+	timestamp     time.Time
+}
+
+// behaviorTracker 记录每个工作负载的历史期望副本数和伸缩事件，用于稳定窗口和速率策略
+type behaviorTracker struct {
+	mu              sync.Mutex
+	recommendations map[string][]timestampedRecommendation
+	scaleUpEvents   map[string][]scaleEvent
+	scaleDownEvents map[string][]scaleEvent
+}
+
+func newBehaviorTracker() *behaviorTracker {
+	return &behaviorTracker{
+		recommendations: make(map[string][]timestampedRecommendation),
+		scaleUpEvents:   make(map[string][]scaleEvent),
+		scaleDownEvents: make(map[string][]scaleEvent),
+	}
+}
+
+// forget 删除工作负载的期望副本数和伸缩事件
+func (t *behaviorTracker) forget(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.recommendations, key)
+	delete(t.scaleUpEvents, key)
+	delete(t.scaleDownEvents, key)
+}
+
+// stabilize 记录本次期望副本数，并按稳定窗口返回稳定后的副本数：
+// 扩容取窗口内期望值的最小值，缩容取窗口内期望值的最大值
+func (t *behaviorTracker) stabilize(key string, now time.Time, currentReplicas, desiredReplicas int32,
+	scaleUp, scaleDown *autoscalingv2.HPAScalingRules) int32 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	upWindow := time.Duration(*scaleUp.StabilizationWindowSeconds) * time.Second
+	downWindow := time.Duration(*scaleDown.StabilizationWindowSeconds) * time.Second
+	longestWindow := upWindow
+	if downWindow > longestWindow {
+		longestWindow = downWindow
+	}
+
+	upRecommendation := desiredReplicas
+	downRecommendation := desiredReplicas
+	kept := t.recommendations[key][:0]
+	for _, rec := range t.recommendations[key] {
+		age := now.Sub(rec.timestamp)
+		if age > longestWindow {
+			continue
+		}
+		kept = append(kept, rec)
+		if age <= upWindow && rec.replicas < upRecommendation {
+			upRecommendation = rec.replicas
+		}
+		if age <= downWindow && rec.replicas > downRecommendation {
+			downRecommendation = rec.replicas
+		}
+	}
+	t.recommendations[key] = append(kept, timestampedRecommendation{replicas: desiredReplicas, timestamp: now})
+
+	stabilized := currentReplicas
+	if stabilized < upRecommendation {
+		stabilized = upRecommendation
+	}
+	if stabilized > downRecommendation {
+		stabilized = downRecommendation
+	}
+	return stabilized
+}
+
+// scaleUpLimit 按 scaleUp 的速率策略计算当前允许扩容到的副本数上限
+func (t *behaviorTracker) scaleUpLimit(key string, now time.Time, currentReplicas int32, rules *autoscalingv2.HPAScalingRules) int32 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if *rules.SelectPolicy == autoscalingv2.DisabledPolicySelect {
+		return currentReplicas
+	}
+
+	var result int32
+	selectFn := maxInt32
+	if *rules.SelectPolicy == autoscalingv2.MinChangePolicySelect {
+		result = math.MaxInt32
+		selectFn = minInt32
+	}
+	for _, policy := range rules.Policies {
+		period := time.Duration(policy.PeriodSeconds) * time.Second
+		added := replicasChangedInPeriod(t.scaleUpEvents[key], now, period)
+		deleted := replicasChangedInPeriod(t.scaleDownEvents[key], now, period)
+		periodStartReplicas := currentReplicas - added + deleted
+
+		var proposed int32
+		if policy.Type == autoscalingv2.PodsScalingPolicy {
+			proposed = periodStartReplicas + policy.Value
+		} else {
+			proposed = int32(math.Ceil(float64(periodStartReplicas) * (1 + float64(policy.Value)/100)))
+		}
+		result = selectFn(result, proposed)
+	}
+	return result
+}
+
+// scaleDownLimit 按 scaleDown 的速率策略计算当前允许缩容到的副本数下限
+func (t *behaviorTracker) scaleDownLimit(key string, now time.Time, currentReplicas int32, rules *autoscalingv2.HPAScalingRules) int32 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if *rules.SelectPolicy == autoscalingv2.DisabledPolicySelect {
+		return currentReplicas
+	}
+
+	// 缩容时“变化最大”对应副本数下限最小
+	var result int32
+	selectFn := maxInt32
+	if *rules.SelectPolicy == autoscalingv2.MaxChangePolicySelect {
+		result = math.MaxInt32
+		selectFn = minInt32
+	}
+	for _, policy := range rules.Policies {
+		period := time.Duration(policy.PeriodSeconds) * time.Second
+		added := replicasChangedInPeriod(t.scaleUpEvents[key], now, period)
+		deleted := replicasChangedInPeriod(t.scaleDownEvents[key], now, period)
+		periodStartReplicas := currentReplicas - added + deleted
+
+		var proposed int32
+		if policy.Type == autoscalingv2.PodsScalingPolicy {
+			proposed = periodStartReplicas - policy.Value
+		} else {
+			proposed = int32(float64(periodStartReplicas) * (1 - float64(policy.Value)/100))
+		}
+		result = selectFn(result, proposed)
+	}
+	return result
+}
+
+// recordScaleEvent 记录一次实际的伸缩，并清理超过最长策略周期的旧事件
+func (t *behaviorTracker) recordScaleEvent(key string, now time.Time, currentReplicas, newReplicas int32,
+	scaleUp, scaleDown *autoscalingv2.HPAScalingRules) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch {
+	case newReplicas > currentReplicas:
+		events := pruneScaleEvents(t.scaleUpEvents[key], now, longestPolicyPeriod(scaleUp))
+		t.scaleUpEvents[key] = append(events, scaleEvent{replicaChange: newReplicas - currentReplicas, timestamp: now})
+	case newReplicas < currentReplicas:
+		events := pruneScaleEvents(t.scaleDownEvents[key], now, longestPolicyPeriod(scaleDown))
+		t.scaleDownEvents[key] = append(events, scaleEvent{replicaChange: currentReplicas - newReplicas, timestamp: now})
+	}
+}
+
+// replicasChangedInPeriod 统计 period 内伸缩事件变化的副本总数
+func replicasChangedInPeriod(events []scaleEvent, now time.Time, period time.Duration) int32 {
+	var changed int32
+	for _, event := range events {
+		if now.Sub(event.timestamp) <= period {
+			changed += event.replicaChange
+		}
+	}
+	return changed
+}
+
+// pruneScaleEvents 丢弃超过 period 的伸缩事件
+func pruneScaleEvents(events []scaleEvent, now time.Time, period time.Duration) []scaleEvent {
+	kept := events[:0]
+	for _, event := range events {
+		if now.Sub(event.timestamp) <= period {
+			kept = append(kept, event)
+		}
+	}
+	return kept
+}
+
+// longestPolicyPeriod 返回规则中最长的策略周期
+func longestPolicyPeriod(rules *autoscalingv2.HPAScalingRules) time.Duration {
+	var longest int32
+	for _, policy := range rules.Policies {
+		if policy.PeriodSeconds > longest {
+			longest = policy.PeriodSeconds
+		}
+	}
+	return time.Duration(longest) * time.Second
+}
+
+func maxInt32(a, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt32(a, b int32) int32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// applyBehavior 对策略给出的期望副本数依次应用稳定窗口和速率策略，
+// 返回最终副本数以及稳定窗口生效时的原因
+func (s *ScalingManager) applyBehavior(hpa *autoscalingv1.HPAModifier, currentReplicas, desiredReplicas int32,
+	decision *ScalingDecision) (int32, string) {
+	behavior := hpa.Spec.Behavior
+	scaleUp := scalingRules(behavior.ScaleUp, DefaultScaleUpRules())
+	scaleDown := scalingRules(behavior.ScaleDown, DefaultScaleDownRules())
+	key := workloadKey(hpa)
+	now := time.Now()
+
+	stabilized := s.behavior.stabilize(key, now, currentReplicas, desiredReplicas, scaleUp, scaleDown)
+	var stabilizedReason string
+	if stabilized != desiredReplicas {
+		if desiredReplicas > stabilized {
+			stabilizedReason = autoscalingv1.ReasonScaleUpStabilized
+		} else {
+			stabilizedReason = autoscalingv1.ReasonScaleDownStabilized
+		}
+		decision.adjust(StageStabilization, desiredReplicas, stabilized, stabilizedReason)
+	}
+
+	replicas := stabilized
+	if stabilized > currentReplicas {
+		limit := s.behavior.scaleUpLimit(key, now, currentReplicas, scaleUp)
+		if limit < currentReplicas {
+			limit = currentReplicas
+		}
+		if replicas > limit {
+			replicas = limit
+			message := fmt.Sprintf("the desired replica count %d is limited by the scaleUp policies to %d", stabilized, limit)
+			setCondition(hpa, autoscalingv1.ConditionScalingLimited, metav1.ConditionTrue, autoscalingv1.ReasonScaleUpLimit, message)
+			decision.adjust(StageRateLimit, stabilized, limit, message)
+		}
+	} else if stabilized < currentReplicas {
+		limit := s.behavior.scaleDownLimit(key, now, currentReplicas, scaleDown)
+		if limit > currentReplicas {
+			limit = currentReplicas
+		}
+		if replicas < limit {
+			replicas = limit
+			message := fmt.Sprintf("the desired replica count %d is limited by the scaleDown policies to %d", stabilized, limit)
+			setCondition(hpa, autoscalingv1.ConditionScalingLimited, metav1.ConditionTrue, autoscalingv1.ReasonScaleDownLimit, message)
+			decision.adjust(StageRateLimit, stabilized, limit, message)
+		}
+	}
+
+	// 速率策略不能突破 MinReplicas/MaxReplicas
+	limited := replicas
+	if replicas > hpa.Spec.MaxReplicas {
+		replicas = hpa.Spec.MaxReplicas
+	}
+	if replicas < hpa.Spec.MinReplicas {
+		replicas = hpa.Spec.MinReplicas
+	}
+	decision.adjust(StageReplicaLimits, limited, replicas, "")
+	return replicas, stabilizedReason
+}
+
+// recordScale 在副本数更新成功后记录伸缩事件，供后续的速率策略计算
+func (s *ScalingManager) recordScale(hpa *autoscalingv1.HPAModifier, currentReplicas, newReplicas int32) {
+	if hpa.Spec.Behavior == nil {
+		return
+	}
+	scaleUp := scalingRules(hpa.Spec.Behavior.ScaleUp, DefaultScaleUpRules())
+	scaleDown := scalingRules(hpa.Spec.Behavior.ScaleDown, DefaultScaleDownRules())
+	s.behavior.recordScaleEvent(workloadKey(hpa), time.Now(), currentReplicas, newReplicas, scaleUp, scaleDown)
+}
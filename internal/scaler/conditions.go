@@ -0,0 +1,30 @@
+package scaler
+
+import (
+	"errors"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+)
+
+// setCondition 设置 HPAModifier 的状态条件，状态未变化时保留原有的 LastTransitionTime
+func setCondition(hpa *autoscalingv1.HPAModifier, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&hpa.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: hpa.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// conditionReason 目标无法伸缩时使用 TargetError 的原因，否则使用默认原因
+func conditionReason(err error, defaultReason string) string {
+	var targetErr *TargetError
+	if errors.As(err, &targetErr) {
+		return targetErr.Reason
+	}
+	return defaultReason
+}
@@ -0,0 +1,297 @@
+package scaler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+)
+
+// DecisionAction 一次伸缩决策的最终动作
+type DecisionAction string
+
+const (
+	ActionScaleUp   DecisionAction = "ScaleUp"
+	ActionScaleDown DecisionAction = "ScaleDown"
+	ActionNone      DecisionAction = "None" // 期望副本数与当前副本数相同
+	// ActionDelayed 期望副本数已变化，但仍在策略的伸缩延迟内
+	ActionDelayed DecisionAction = "Delayed"
+	// ActionModifyNativeHPA 目标由原生 HPA 管理，期望副本数写入 HPA 的下限
+	ActionModifyNativeHPA DecisionAction = "ModifyNativeHPA"
+	// ActionSkipped 目标由原生 HPA 管理且 spec.nativeHPA 不为 Modify，不伸缩
+	ActionSkipped DecisionAction = "Skipped"
+	ActionFailed  DecisionAction = "Failed"
+)
+
+// DecisionStage 修改副本数建议的环节
+type DecisionStage string
+
+const (
+	StageTolerance      DecisionStage = "Tolerance"      // 负载比率落在容差带内，保持当前副本数
+	StageMissingMetrics DecisionStage = "MissingMetrics" // 部分指标获取失败，不缩容
+	StageReplicaLimits  DecisionStage = "ReplicaLimits"  // minReplicas 和 maxReplicas
+	StagePreWarm        DecisionStage = "PreWarm"        // 按预测的峰值提前扩容
+	StageStabilization  DecisionStage = "Stabilization"  // behavior 的稳定窗口
+	StageRateLimit      DecisionStage = "RateLimit"      // behavior 的速率策略
+	StageScalingDelay   DecisionStage = "ScalingDelay"   // 策略的伸缩延迟
+)
+
+// MetricInput 一个指标的当前值和预测峰值，单位与对应的目标值相同
+type MetricInput struct {
+	Name    string  `json:"name"`
+	Current float64 `json:"current"`
+	// Peak 预测窗口内的峰值，预测不可用时等于当前值
+	Peak   float64 `json:"peak"`
+	Target float64 `json:"target,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// ForecastSummary 本次决策使用的预测
+type ForecastSummary struct {
+	// Source 内置预测器的名称，使用外部预测服务时为 predictor
+	Source         string `json:"source"`
+	HorizonSeconds int64  `json:"horizonSeconds"`
+	Available      bool   `json:"available"`
+	Error          string `json:"error,omitempty"`
+	// PreWarm 计划中的预热扩容
+	PreWarm *autoscalingv1.PreWarmPlan `json:"preWarm,omitempty"`
+}
+
+// DecisionInputs 决策的输入
+type DecisionInputs struct {
+	Metrics         []MetricInput                     `json:"metrics,omitempty"`
+	Forecast        ForecastSummary                   `json:"forecast"`
+	Pattern         string                            `json:"pattern,omitempty"`
+	Strategy        string                            `json:"strategy,omitempty"`
+	ThresholdMode   autoscalingv1.ThresholdMode       `json:"thresholdMode,omitempty"`
+	Aggregation     autoscalingv1.AggregationFunction `json:"aggregation,omitempty"`
+	CPUThreshold    float64                           `json:"cpuThreshold,omitempty"`
+	MemoryThreshold float64                           `json:"memoryThreshold,omitempty"`
+	// ToleranceBand 不伸缩的负载比率区间 [lower, upper]
+	ToleranceBand    [2]float64 `json:"toleranceBand"`
+	CurrentReplicas  int32      `json:"currentReplicas"`
+	ReadyReplicas    int32      `json:"readyReplicas"`
+	StartingReplicas int32      `json:"startingReplicas,omitempty"`
+	MinReplicas      int32      `json:"minReplicas"`
+	MaxReplicas      int32      `json:"maxReplicas"`
+}
+
+// ReplicaProposal 一个指标或预热计划给出的副本数建议
+type ReplicaProposal struct {
+	Source   string  `json:"source"`
+	Ratio    float64 `json:"ratio,omitempty"`
+	Replicas int32   `json:"replicas"`
+}
+
+// DecisionAdjustment 一个环节对副本数建议的修改
+type DecisionAdjustment struct {
+	Stage   DecisionStage `json:"stage"`
+	From    int32         `json:"from"`
+	To      int32         `json:"to"`
+	Message string        `json:"message,omitempty"`
+}
+
+// ScalingDecision 一次调谐的伸缩决策：输入、每个中间建议、依次生效的修改和最终动作。
+// 方法允许 nil 接收者，不需要记录决策的调用方传 nil 即可
+type ScalingDecision struct {
+	Namespace       string               `json:"namespace"`
+	Name            string               `json:"name"`
+	Target          string               `json:"target"`
+	Time            time.Time            `json:"time"`
+	Inputs          DecisionInputs       `json:"inputs"`
+	Proposals       []ReplicaProposal    `json:"proposals,omitempty"`
+	Adjustments     []DecisionAdjustment `json:"adjustments,omitempty"`
+	DesiredReplicas int32                `json:"desiredReplicas"`
+	Action          DecisionAction       `json:"action"`
+	// Changed 本次是否修改了目标的副本数或原生 HPA
+	Changed bool `json:"changed"`
+	// Reason 和 Message 取自 AbleToScale 条件，失败时为错误信息
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// newScalingDecision 创建决策并记录 spec 中的输入
+func newScalingDecision(hpa *autoscalingv1.HPAModifier, now time.Time) *ScalingDecision {
+	lower, upper := toleranceBand(hpa)
+	decision := &ScalingDecision{
+		Namespace: hpa.Namespace,
+		Name:      hpa.Name,
+		Target:    fmt.Sprintf("%s/%s", hpa.Spec.TargetRef.Kind, hpa.Spec.TargetRef.Name),
+		Time:      now,
+		Inputs: DecisionInputs{
+			Forecast:      ForecastSummary{Source: forecastSource(hpa), HorizonSeconds: int64(predictionHorizon(hpa) / time.Second)},
+			ThresholdMode: hpa.Spec.ThresholdMode,
+			Aggregation:   aggregationFunction(hpa),
+			ToleranceBand: [2]float64{lower, upper},
+			MinReplicas:   hpa.Spec.MinReplicas,
+			MaxReplicas:   hpa.Spec.MaxReplicas,
+		},
+	}
+	if len(hpa.Spec.Metrics) == 0 {
+		decision.Inputs.CPUThreshold = hpa.Spec.CPUThreshold
+		decision.Inputs.MemoryThreshold = hpa.Spec.MemoryThreshold
+	}
+	return decision
+}
+
+// forecastSource 返回预测来源的名称
+func forecastSource(hpa *autoscalingv1.HPAModifier) string {
+	if forecaster := NewForecaster(hpa.Spec.Forecaster); forecaster != nil {
+		return forecaster.Name()
+	}
+	return "predictor"
+}
+
+// addMetric 记录一个指标的输入
+func (d *ScalingDecision) addMetric(input MetricInput) {
+	if d != nil {
+		d.Inputs.Metrics = append(d.Inputs.Metrics, input)
+	}
+}
+
+// setForecastError 记录预测是否可用
+func (d *ScalingDecision) setForecastError(err error) {
+	if d == nil {
+		return
+	}
+	d.Inputs.Forecast.Available = err == nil
+	d.Inputs.Forecast.Error = ""
+	if err != nil {
+		d.Inputs.Forecast.Error = err.Error()
+	}
+}
+
+// propose 记录一个副本数建议
+func (d *ScalingDecision) propose(source string, ratio float64, replicas int32) {
+	if d != nil {
+		d.Proposals = append(d.Proposals, ReplicaProposal{Source: source, Ratio: ratio, Replicas: replicas})
+	}
+}
+
+// adjust 记录一个环节把副本数建议从 from 改为 to，未改变时不记录
+func (d *ScalingDecision) adjust(stage DecisionStage, from, to int32, message string) {
+	if d != nil && from != to {
+		d.Adjustments = append(d.Adjustments, DecisionAdjustment{Stage: stage, From: from, To: to, Message: message})
+	}
+}
+
+// finish 记录最终动作，说明取自 AbleToScale 条件
+func (d *ScalingDecision) finish(hpa *autoscalingv1.HPAModifier, action DecisionAction) {
+	d.Action = action
+	d.DesiredReplicas = hpa.Status.DesiredReplicas
+	if condition := meta.FindStatusCondition(hpa.Status.Conditions, autoscalingv1.ConditionAbleToScale); condition != nil {
+		d.Reason = condition.Reason
+		d.Message = condition.Message
+	}
+}
+
+// fail 记录失败的决策
+func (d *ScalingDecision) fail(err error) {
+	d.Action = ActionFailed
+	d.Reason = conditionReason(err, autoscalingv1.ReasonReconcileFailed)
+	d.Message = err.Error()
+}
+
+// NeedsEvent 判断决策是否值得创建 Event：修改了副本数或原生 HPA，或动作、原因与上一次决策不同。
+// 副本数不变的决策不创建 Event，避免每次调谐都产生 Event
+func (d *ScalingDecision) NeedsEvent(previous *ScalingDecision) bool {
+	if d.Action == ActionNone {
+		return false
+	}
+	return d.Changed || previous == nil || previous.Action != d.Action || previous.Reason != d.Reason
+}
+
+// Summary 返回一行决策说明，用于日志和事件
+func (d *ScalingDecision) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d -> %d replicas", d.Action, d.Inputs.CurrentReplicas, d.DesiredReplicas)
+	if len(d.Proposals) > 0 {
+		proposals := make([]string, len(d.Proposals))
+		for i, proposal := range d.Proposals {
+			proposals[i] = fmt.Sprintf("%s=%d", proposal.Source, proposal.Replicas)
+			if proposal.Ratio > 0 {
+				proposals[i] += fmt.Sprintf(" (ratio %.2f)", proposal.Ratio)
+			}
+		}
+		fmt.Fprintf(&b, "; proposals %s", strings.Join(proposals, ", "))
+	}
+	for _, adjustment := range d.Adjustments {
+		fmt.Fprintf(&b, "; %s %d -> %d", adjustment.Stage, adjustment.From, adjustment.To)
+	}
+	if d.Message != "" {
+		fmt.Fprintf(&b, "; %s", d.Message)
+	}
+	return b.String()
+}
+
+// DecisionLog 保存每个 HPAModifier 最近一次的伸缩决策，并以 JSON 提供给调试端点
+type DecisionLog struct {
+	mu        sync.RWMutex
+	decisions map[string]*ScalingDecision
+}
+
+// NewDecisionLog 创建决策记录
+func NewDecisionLog() *DecisionLog {
+	return &DecisionLog{decisions: make(map[string]*ScalingDecision)}
+}
+
+// Record 保存决策，覆盖同一 HPAModifier 之前的决策
+func (l *DecisionLog) Record(decision *ScalingDecision) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.decisions[decision.Namespace+"/"+decision.Name] = decision
+}
+
+// Get 返回 HPAModifier 最近一次的决策，没有时返回 nil
+func (l *DecisionLog) Get(namespace, name string) *ScalingDecision {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.decisions[namespace+"/"+name]
+}
+
+// Forget 删除 HPAModifier 的决策
+func (l *DecisionLog) Forget(namespace, name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.decisions, namespace+"/"+name)
+}
+
+// List 返回所有决策，按 namespace/name 排序。namespace 或 name 不为空时只返回匹配的决策
+func (l *DecisionLog) List(namespace, name string) []*ScalingDecision {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	decisions := make([]*ScalingDecision, 0, len(l.decisions))
+	for _, decision := range l.decisions {
+		if (namespace == "" || decision.Namespace == namespace) && (name == "" || decision.Name == name) {
+			decisions = append(decisions, decision)
+		}
+	}
+	sort.Slice(decisions, func(i, j int) bool {
+		if decisions[i].Namespace != decisions[j].Namespace {
+			return decisions[i].Namespace < decisions[j].Namespace
+		}
+		return decisions[i].Name < decisions[j].Name
+	})
+	return decisions
+}
+
+// ServeHTTP 返回最近的决策，可用 namespace 和 name 查询参数过滤
+func (l *DecisionLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(l.List(query.Get("namespace"), query.Get("name")))
+}
@@ -0,0 +1,245 @@
+package scaler
+
+import (
+	"fmt"
+	"math"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+)
+
+// Forecaster 定义进程内的负载预测接口
+type Forecaster interface {
+	// Name 返回预测器名称
+	Name() string
+	// Forecast 基于等间隔的历史数据预测之后 steps 个点
+	Forecast(history []float64, steps int) ([]float64, error)
+}
+
+// NewForecaster 根据 spec.forecaster 创建内置预测器，使用外部预测服务时返回 nil
+func NewForecaster(forecasterType autoscalingv1.ForecasterType) Forecaster {
+	switch forecasterType {
+	case autoscalingv1.ForecasterHoltWinters:
+		return NewHoltWintersForecaster()
+	case autoscalingv1.ForecasterARIMA:
+		return NewARIMAForecaster()
+	default:
+		return nil
+	}
+}
+
+// HoltWintersForecaster 加性季节的 Holt-Winters 三次指数平滑
+type HoltWintersForecaster struct {
+	Alpha float64 // 水平平滑系数
+	Beta  float64 // 趋势平滑系数
+	Gamma float64 // 季节平滑系数
+	// SeasonLength 一个季节周期包含的点数，为 0 时根据自相关函数估计
+	SeasonLength int
+}
+
+// NewHoltWintersForecaster 创建使用默认平滑系数的 Holt-Winters 预测器
+func NewHoltWintersForecaster() *HoltWintersForecaster {
+	return &HoltWintersForecaster{
+		Alpha: 0.5,
+		Beta:  0.1,
+		Gamma: 0.3,
+	}
+}
+
+func (f *HoltWintersForecaster) Name() string {
+	return string(autoscalingv1.ForecasterHoltWinters)
+}
+
+func (f *HoltWintersForecaster) Forecast(history []float64, steps int) ([]float64, error) {
+	if len(history) == 0 {
+		return nil, fmt.Errorf("no history to forecast from")
+	}
+	if steps < 1 {
+		steps = 1
+	}
+
+	season := f.SeasonLength
+	if season == 0 {
+		season = estimateSeasonLength(history)
+	}
+	// 至少需要两个完整周期才能初始化季节分量，否则退化为 Holt 线性趋势模型
+	if season < 2 || len(history) < 2*season {
+		return holtLinear(history, steps, f.Alpha, f.Beta), nil
+	}
+
+	// 用前两个周期初始化水平、趋势和季节分量
+	level := calculateMean(history[:season])
+	trend := (calculateMean(history[season:2*season]) - level) / float64(season)
+	seasonal := make([]float64, season)
+	for i := 0; i < season; i++ {
+		seasonal[i] = history[i] - level
+	}
+
+	for t := season; t < len(history); t++ {
+		lastLevel := level
+		lastSeasonal := seasonal[t%season]
+		level = f.Alpha*(history[t]-lastSeasonal) + (1-f.Alpha)*(level+trend)
+		trend = f.Beta*(level-lastLevel) + (1-f.Beta)*trend
+		seasonal[t%season] = f.Gamma*(history[t]-level) + (1-f.Gamma)*lastSeasonal
+	}
+
+	n := len(history)
+	forecast := make([]float64, steps)
+	for h := 1; h <= steps; h++ {
+		forecast[h-1] = math.Max(0, level+float64(h)*trend+seasonal[(n+h-1)%season])
+	}
+	return forecast, nil
+}
+
+// holtLinear Holt 双指数平滑，只建模水平和趋势
+func holtLinear(history []float64, steps int, alpha, beta float64) []float64 {
+	level := history[0]
+	trend := 0.0
+	if len(history) > 1 {
+		trend = history[1] - history[0]
+	}
+	for t := 1; t < len(history); t++ {
+		lastLevel := level
+		level = alpha*history[t] + (1-alpha)*(level+trend)
+		trend = beta*(level-lastLevel) + (1-beta)*trend
+	}
+
+	forecast := make([]float64, steps)
+	for h := 1; h <= steps; h++ {
+		forecast[h-1] = math.Max(0, level+float64(h)*trend)
+	}
+	return forecast
+}
+
+// estimateSeasonLength 取自相关函数第一个显著峰值的滞后阶数作为季节长度，没有时返回 0
+func estimateSeasonLength(history []float64) int {
+	autocorr := autocorrelation(history)
+	for lag := 2; lag < len(autocorr)-1; lag++ {
+		if autocorr[lag] > autocorr[lag-1] && autocorr[lag] > autocorr[lag+1] && autocorr[lag] > 0.5 {
+			return lag
+		}
+	}
+	return 0
+}
+
+// ARIMAForecaster 简化的 ARIMA(p,d,0) 模型：对 d 阶差分后的序列拟合 AR(p)，系数由 Yule-Walker 方程求解
+type ARIMAForecaster struct {
+	P int // 自回归阶数
+	D int // 差分阶数
+}
+
+// NewARIMAForecaster 创建 ARIMA(2,1,0) 预测器
+func NewARIMAForecaster() *ARIMAForecaster {
+	return &ARIMAForecaster{
+		P: 2,
+		D: 1,
+	}
+}
+
+func (f *ARIMAForecaster) Name() string {
+	return string(autoscalingv1.ForecasterARIMA)
+}
+
+func (f *ARIMAForecaster) Forecast(history []float64, steps int) ([]float64, error) {
+	if len(history) == 0 {
+		return nil, fmt.Errorf("no history to forecast from")
+	}
+	if steps < 1 {
+		steps = 1
+	}
+
+	// 差分，并记录每一阶的最后一个值用于还原
+	series := history
+	var lastValues []float64
+	for d := 0; d < f.D && len(series) > 1; d++ {
+		lastValues = append(lastValues, series[len(series)-1])
+		series = difference(series)
+	}
+
+	// 数据太少时退化为低阶模型
+	p := f.P
+	if p > len(series)/2 {
+		p = len(series) / 2
+	}
+	phi := yuleWalker(series, p)
+	mean := calculateMean(series)
+
+	// 递推预测差分序列
+	extended := make([]float64, len(series), len(series)+steps)
+	copy(extended, series)
+	for h := 0; h < steps; h++ {
+		next := mean
+		for i, coef := range phi {
+			next += coef * (extended[len(extended)-1-i] - mean)
+		}
+		extended = append(extended, next)
+	}
+	forecast := extended[len(series):]
+
+	// 逐阶累加还原到原始序列
+	for d := len(lastValues) - 1; d >= 0; d-- {
+		sum := lastValues[d]
+		for i := range forecast {
+			sum += forecast[i]
+			forecast[i] = sum
+		}
+	}
+
+	for i := range forecast {
+		forecast[i] = math.Max(0, forecast[i])
+	}
+	return forecast, nil
+}
+
+// difference 计算一阶差分
+func difference(data []float64) []float64 {
+	diff := make([]float64, len(data)-1)
+	for i := 1; i < len(data); i++ {
+		diff[i-1] = data[i] - data[i-1]
+	}
+	return diff
+}
+
+// yuleWalker 使用 Levinson-Durbin 递推求解 AR(p) 的 Yule-Walker 方程
+func yuleWalker(data []float64, p int) []float64 {
+	if p <= 0 {
+		return nil
+	}
+
+	// 自协方差
+	mean := calculateMean(data)
+	autocov := make([]float64, p+1)
+	for k := 0; k <= p; k++ {
+		for t := 0; t+k < len(data); t++ {
+			autocov[k] += (data[t] - mean) * (data[t+k] - mean)
+		}
+		autocov[k] /= float64(len(data))
+	}
+
+	phi := make([]float64, p)
+	if autocov[0] == 0 {
+		return phi
+	}
+
+	variance := autocov[0]
+	for k := 1; k <= p; k++ {
+		acc := autocov[k]
+		for j := 1; j < k; j++ {
+			acc -= phi[j-1] * autocov[k-j]
+		}
+		reflection := acc / variance
+
+		next := make([]float64, p)
+		copy(next, phi)
+		next[k-1] = reflection
+		for j := 1; j < k; j++ {
+			next[j-1] = phi[j-1] - reflection*phi[k-j-1]
+		}
+		phi = next
+
+		variance *= 1 - reflection*reflection
+		if variance <= 0 {
+			break
+		}
+	}
+	return phi
+}
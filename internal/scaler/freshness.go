@@ -0,0 +1,78 @@
+package scaler
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+)
+
+const (
+	// DefaultMaxMetricAge 未设置 spec.metricFreshness.maxAgeSeconds 时样本的最长时间
+	DefaultMaxMetricAge = 2 * time.Minute
+	// DefaultMaxMetricWindow 未设置 spec.metricFreshness.maxWindowSeconds 时样本统计窗口的最大长度
+	DefaultMaxMetricWindow = 5 * time.Minute
+)
+
+// StaleMetricsError 所有 Pod 的指标样本都超出新鲜度限制
+type StaleMetricsError struct {
+	Pods      int
+	MaxAge    time.Duration
+	MaxWindow time.Duration
+}
+
+func (e *StaleMetricsError) Error() string {
+	return fmt.Sprintf("all %d pod metric samples are older than %s or averaged over more than %s, the metrics source may be lagging",
+		e.Pods, e.MaxAge, e.MaxWindow)
+}
+
+// metricFreshnessLimits 返回样本的最长时间和统计窗口的最大长度
+func metricFreshnessLimits(hpa *autoscalingv1.HPAModifier) (time.Duration, time.Duration) {
+	maxAge, maxWindow := DefaultMaxMetricAge, DefaultMaxMetricWindow
+	if freshness := hpa.Spec.MetricFreshness; freshness != nil {
+		if freshness.MaxAgeSeconds > 0 {
+			maxAge = time.Duration(freshness.MaxAgeSeconds) * time.Second
+		}
+		if freshness.MaxWindowSeconds > 0 {
+			maxWindow = time.Duration(freshness.MaxWindowSeconds) * time.Second
+		}
+	}
+	return maxAge, maxWindow
+}
+
+// isStaleSample 判断样本是否过期或统计窗口过长。没有时间戳或窗口的样本无法判断，按新鲜处理
+func isStaleSample(item metricsv1beta1.PodMetrics, now time.Time, maxAge, maxWindow time.Duration) bool {
+	if !item.Timestamp.IsZero() && now.Sub(item.Timestamp.Time) > maxAge {
+		return true
+	}
+	return item.Window.Duration > maxWindow
+}
+
+// isDuplicateSample 判断本次调谐收集的样本是否与上次相同，重复的样本不写入历史数据
+func isDuplicateSample(hpa *autoscalingv1.HPAModifier) bool {
+	condition := meta.FindStatusCondition(hpa.Status.Conditions, autoscalingv1.ConditionMetricsStale)
+	return condition != nil && condition.Status == metav1.ConditionTrue && condition.Reason == autoscalingv1.ReasonDuplicateSample
+}
+
+// setFreshnessCondition 根据本次收集的结果设置 MetricsStale 条件，previous 为收集前的 status.lastSampleTime
+func setFreshnessCondition(hpa *autoscalingv1.HPAModifier, previous *metav1.Time) {
+	maxAge, maxWindow := metricFreshnessLimits(hpa)
+	latest := hpa.Status.LastSampleTime
+	switch {
+	case hpa.Status.ExcludedPods.Stale > 0:
+		setCondition(hpa, autoscalingv1.ConditionMetricsStale, metav1.ConditionTrue, autoscalingv1.ReasonStaleSamples,
+			fmt.Sprintf("%d pod metric samples are older than %s or averaged over more than %s and were skipped",
+				hpa.Status.ExcludedPods.Stale, maxAge, maxWindow))
+	case previous != nil && latest != nil && !latest.After(previous.Time):
+		setCondition(hpa, autoscalingv1.ConditionMetricsStale, metav1.ConditionTrue, autoscalingv1.ReasonDuplicateSample,
+			fmt.Sprintf("the metrics source has not produced a sample newer than %s, history is not updated",
+				previous.UTC().Format(time.RFC3339)))
+	default:
+		setCondition(hpa, autoscalingv1.ConditionMetricsStale, metav1.ConditionFalse, autoscalingv1.ReasonMetricsFresh,
+			"pod metric samples are within the freshness limits")
+	}
+}
@@ -0,0 +1,141 @@
+package scaler
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sample 带时间戳的指标样本
+type Sample struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+}
+
+// bucket 一个采样间隔内的样本汇总
+type bucket struct {
+	start time.Time
+	sum   float64
+	count int
+}
+
+func (b bucket) mean() float64 {
+	return b.sum / float64(b.count)
+}
+
+// HistoryStore 并发安全的时间序列存储，每个键一条序列。
+// 样本按采样间隔分桶取平均，只保留最新样本之前 window 内的数据
+type HistoryStore struct {
+	mu       sync.RWMutex
+	window   time.Duration
+	interval time.Duration
+	series   map[string][]bucket
+}
+
+// NewHistoryStore 创建时间序列存储
+func NewHistoryStore(window, interval time.Duration) *HistoryStore {
+	return &HistoryStore{
+		window:   window,
+		interval: interval,
+		series:   make(map[string][]bucket),
+	}
+}
+
+// Interval 返回采样间隔
+func (h *HistoryStore) Interval() time.Duration {
+	return h.interval
+}
+
+// Window 返回保留历史数据的时间窗口
+func (h *HistoryStore) Window() time.Duration {
+	return h.window
+}
+
+// Add 记录一个样本，允许乱序写入（如回填历史数据）
+func (h *HistoryStore) Add(key string, timestamp time.Time, value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	start := timestamp.Truncate(h.interval)
+	buckets := h.series[key]
+	i := sort.Search(len(buckets), func(i int) bool {
+		return !buckets[i].start.Before(start)
+	})
+	if i < len(buckets) && buckets[i].start.Equal(start) {
+		buckets[i].sum += value
+		buckets[i].count++
+	} else {
+		buckets = append(buckets, bucket{})
+		copy(buckets[i+1:], buckets[i:])
+		buckets[i] = bucket{start: start, sum: value, count: 1}
+	}
+
+	// 丢弃窗口之外的旧数据
+	cutoff := buckets[len(buckets)-1].start.Add(-h.window)
+	expired := sort.Search(len(buckets), func(i int) bool {
+		return buckets[i].start.After(cutoff)
+	})
+	h.series[key] = buckets[expired:]
+}
+
+// Samples 返回按采样间隔重采样后的序列，缺失的间隔按相邻两点线性插值
+func (h *HistoryStore) Samples(key string) []Sample {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	buckets := h.series[key]
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	samples := []Sample{{Timestamp: buckets[0].start, Value: buckets[0].mean()}}
+	for i := 1; i < len(buckets); i++ {
+		prev, next := buckets[i-1], buckets[i]
+		gaps := int(next.start.Sub(prev.start) / h.interval)
+		for step := 1; step < gaps; step++ {
+			fraction := float64(step) / float64(gaps)
+			samples = append(samples, Sample{
+				Timestamp: prev.start.Add(time.Duration(step) * h.interval),
+				Value:     prev.mean() + fraction*(next.mean()-prev.mean()),
+			})
+		}
+		samples = append(samples, Sample{Timestamp: next.start, Value: next.mean()})
+	}
+	return samples
+}
+
+// Values 返回重采样后序列的值
+func (h *HistoryStore) Values(key string) []float64 {
+	samples := h.Samples(key)
+	values := make([]float64, len(samples))
+	for i, sample := range samples {
+		values[i] = sample.Value
+	}
+	return values
+}
+
+// Keys 返回所有序列的键
+func (h *HistoryStore) Keys() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// DeleteWorkload 删除工作负载的所有序列，包括以 "<workloadKey>#" 开头的辅助指标序列
+func (h *HistoryStore) DeleteWorkload(workloadKey string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for key := range h.series {
+		if key == workloadKey || strings.HasPrefix(key, workloadKey+"#") {
+			delete(h.series, key)
+		}
+	}
+}
@@ -2,244 +2,651 @@ package scaler
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
-	"net/http"
-	"strings"
+	"sync"
 	"time"
 
 	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+	"yemo.info/auto-scaling-system/internal/predictor"
 
+	k8sautoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/scale"
 	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	customclient "k8s.io/metrics/pkg/client/custom_metrics"
+	externalclient "k8s.io/metrics/pkg/client/external_metrics"
+)
+
+// 预测请求的默认参数
+const (
+	// DefaultPredictionWindow spec.predictionWindow 未设置时的预测时间窗口
+	DefaultPredictionWindow = 5 * time.Minute
+	// DefaultPredictionStep 预测序列中相邻两个点的时间间隔
+	DefaultPredictionStep = time.Minute
 )
 
 // PredictionResponse 定义预测服务的响应结构
-type PredictionResponse struct {
-	Values    []float64          `json:"values"`    // 预测值数组
-	Features  map[string]float64 `json:"features"`  // 特征值
-	Timestamp string             `json:"timestamp"` // 预测时间戳
-}
+type PredictionResponse = predictor.PredictionResponse
 
 // MetricsClient 定义指标客户端接口
 type MetricsClient interface {
-	GetPodMetrics(namespace string) (*metricsv1beta1.PodMetricsList, error)
+	GetPodMetrics(ctx context.Context, namespace string, selector labels.Selector) (*metricsv1beta1.PodMetricsList, error)
+}
+
+// MetricsSource 按 HPAModifier 创建 MetricsClient 的指标源，如执行模板化 PromQL 的 Prometheus 指标源
+type MetricsSource interface {
+	ForWorkload(hpa *autoscalingv1.HPAModifier) (MetricsClient, error)
 }
 
 // ScalingManager 管理伸缩决策
 type ScalingManager struct {
-	KubeClient      kubernetes.Interface
-	MetricsClient   MetricsClient
-	PredictorURL    string
+	KubeClient     kubernetes.Interface
+	ScaleClient    scale.ScalesGetter
+	RESTMapper     meta.RESTMapper
+	MetricsClient  MetricsClient
+	PredictorURL   string
+	Predictor      *predictor.Client
+	PredictionStep time.Duration // 预测序列的步长，为 0 时使用 DefaultPredictionStep
+	// PrometheusMetrics 可选，spec.metricsSource 为 Prometheus 的 HPAModifier 使用的指标源
+	PrometheusMetrics MetricsSource
+	// CustomMetrics 和 ExternalMetrics 可选，读取 spec.metrics 中 Pods、Object 和 External 类型的指标
+	CustomMetrics   customclient.CustomMetricsClient
+	ExternalMetrics externalclient.ExternalMetricsClient
+	// HistoryPersister 可选，保存历史快照，使重启或切换 leader 后不丢失已学习的负载模式
+	HistoryPersister HistoryPersister
+	// HistorySource 可选，为没有历史数据的新工作负载回填历史
+	HistorySource HistorySource
+	// Decisions 保存每个 HPAModifier 最近一次的伸缩决策，为空时不保存
+	Decisions       *DecisionLog
 	strategyFactory *StrategyFactory
+	behavior        *behaviorTracker
+
+	mu         sync.Mutex
+	workloads  map[string]string          // HPAModifier 的 namespace/name 到其工作负载键
+	backfilled map[string]bool            // 已尝试回填历史数据的工作负载
+	startups   map[string]*startupSamples // 每个工作负载最近的 Pod 启动耗时
+	// scaleTargets 已确认支持 /scale 子资源的类型
+	scaleTargets map[schema.GroupVersionKind]schema.GroupResource
 }
 
 // NewScalingManager 创建新的伸缩管理器
-func NewScalingManager(kubeClient kubernetes.Interface, metricsClient MetricsClient, predictorURL string) *ScalingManager {
+func NewScalingManager(kubeClient kubernetes.Interface, scaleClient scale.ScalesGetter, restMapper meta.RESTMapper,
+	metricsClient MetricsClient, predictorURL string) *ScalingManager {
 	return &ScalingManager{
 		KubeClient:      kubeClient,
+		ScaleClient:     scaleClient,
+		RESTMapper:      restMapper,
 		MetricsClient:   metricsClient,
 		PredictorURL:    predictorURL,
+		Predictor:       predictor.NewClient(predictor.DefaultOptions()),
 		strategyFactory: NewStrategyFactory(24*time.Hour, 5*time.Minute), // 24小时历史数据，5分钟采样间隔
+		behavior:        newBehaviorTracker(),
+		Decisions:       NewDecisionLog(),
 	}
 }
 
-// CollectMetrics 收集目标工作负载的指标
+// CollectMetrics 收集目标工作负载的指标，单位与 spec.thresholdMode 一致：
+// Absolute 时为每个 Pod 的平均 CPU（核）和内存（GiB），Utilization 时为用量占 requests 的比例
 func (s *ScalingManager) CollectMetrics(ctx context.Context, hpa *autoscalingv1.HPAModifier) (float64, float64, error) {
-	podMetrics, err := s.MetricsClient.GetPodMetrics(hpa.Spec.TargetRef.Namespace)
-	if err != nil {
-		return 0, 0, fmt.Errorf("failed to get pod metrics: %v", err)
-	}
-
-	var totalCPU, totalMemory resource.Quantity
-	podCount := 0
-	for _, pod := range podMetrics.Items {
-		// 使用更可靠的标签匹配逻辑
-		if pod.Labels["app"] == hpa.Spec.TargetRef.Name ||
-			strings.HasPrefix(pod.Name, hpa.Spec.TargetRef.Name+"-") {
-			for _, container := range pod.Containers {
-				cpu := container.Usage.Cpu()
-				memory := container.Usage.Memory()
-				totalCPU.Add(*cpu)
-				totalMemory.Add(*memory)
+	selector, err := s.resolveSelector(ctx, hpa)
+	if err != nil {
+		return 0, 0, err
+	}
+	samples, err := s.collectPodSamples(ctx, hpa, selector)
+	if err != nil {
+		return 0, 0, err
+	}
+	return samples.resourceUsage(hpa, usesUtilization(hpa))
+}
+
+// podSamples 一次调谐中收集并过滤后的目标 Pod 指标，绝对用量和利用率都由同一份数据计算
+type podSamples struct {
+	selector labels.Selector
+	metrics  []metricsv1beta1.PodMetrics
+	pods     map[string]*corev1.Pod
+}
+
+// collectPodSamples 读取 selector 匹配的 Pod 的指标和状态并过滤，同时更新 status.readyReplicas、status.startingReplicas 并记录 Pod 启动耗时
+func (s *ScalingManager) collectPodSamples(ctx context.Context, hpa *autoscalingv1.HPAModifier, selector labels.Selector) (*podSamples, error) {
+	metricsClient, err := s.metricsClientFor(hpa)
+	if err != nil {
+		return nil, err
+	}
+	podMetrics, err := metricsClient.GetPodMetrics(ctx, hpa.Namespace, selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pod metrics: %v", err)
+	}
+
+	// 结合 Pod 状态过滤指标
+	pods, err := s.listPods(ctx, hpa.Namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	hpa.Status.ReadyReplicas, hpa.Status.StartingReplicas = countReadyPods(hpa, pods, podMetrics.Items, now)
+	s.recordPodStartups(hpa, pods)
+	items, err := filterPodMetrics(hpa, podMetrics.Items, pods, now)
+	if err != nil {
+		return nil, err
+	}
+	return &podSamples{selector: selector, metrics: items, pods: pods}, nil
+}
+
+// resourceUsage 返回每个 Pod 的 CPU 和内存用量，utilization 为 true 时返回相对 requests 的利用率
+func (p *podSamples) resourceUsage(hpa *autoscalingv1.HPAModifier, utilization bool) (float64, float64, error) {
+	if utilization {
+		return resourceUtilization(hpa, p.selector, p.metrics, p.pods)
+	}
+
+	// 每个 Pod 的用量为计入的容器之和，再按 spec.aggregation 跨 Pod 汇总
+	var cpuValues, memoryValues []float64
+	for _, pod := range p.metrics {
+		var podCPU, podMemory resource.Quantity
+		containers := 0
+		for _, container := range pod.Containers {
+			if !includeContainer(hpa, container.Name) {
+				continue
 			}
-			podCount++
+			podCPU.Add(*container.Usage.Cpu())
+			podMemory.Add(*container.Usage.Memory())
+			containers++
+		}
+		if containers == 0 {
+			continue
 		}
+		cpuValues = append(cpuValues, float64(podCPU.MilliValue())/1000.0)
+		memoryValues = append(memoryValues, float64(podMemory.Value())/bytesPerGiB) // 转换为GB
 	}
 
-	if podCount == 0 {
-		return 0, 0, fmt.Errorf("no pods found for deployment %s", hpa.Spec.TargetRef.Name)
+	if len(cpuValues) == 0 && len(p.metrics) > 0 {
+		return 0, 0, noSelectedContainersError(len(p.metrics))
+	}
+	if len(cpuValues) == 0 {
+		return 0, 0, fmt.Errorf("no pods found for %s %s matching selector %q",
+			hpa.Spec.TargetRef.Kind, hpa.Spec.TargetRef.Name, p.selector.String())
 	}
 
-	cpuUsage := float64(totalCPU.MilliValue()) / float64(podCount) / 1000.0
-	memoryUsage := float64(totalMemory.Value()) / float64(podCount) / (1024 * 1024 * 1024) // 转换为GB
+	fn := aggregationFunction(hpa)
+	cpuUsage := aggregate(fn, cpuValues)
+	memoryUsage := aggregate(fn, memoryValues)
 
 	return cpuUsage, memoryUsage, nil
 }
 
-// queryPrediction 从预测服务获取预测结果
-func (s *ScalingManager) queryPrediction(metric string) (*PredictionResponse, error) {
-	url := fmt.Sprintf("%s/predict?target=%s", s.PredictorURL, metric)
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query prediction service: %v", err)
+// metricsClientFor 按 spec.metricsSource 选择指标客户端
+func (s *ScalingManager) metricsClientFor(hpa *autoscalingv1.HPAModifier) (MetricsClient, error) {
+	if hpa.Spec.MetricsSource != autoscalingv1.MetricsSourcePrometheus {
+		return s.MetricsClient, nil
+	}
+	if s.PrometheusMetrics == nil {
+		return nil, fmt.Errorf("metricsSource is Prometheus but the controller has no Prometheus configured (--prometheus-url)")
 	}
-	defer resp.Body.Close()
+	return s.PrometheusMetrics.ForWorkload(hpa)
+}
 
-	var result PredictionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode prediction response: %v", err)
+// predictionHorizon 返回 HPAModifier 的预测时间窗口
+func predictionHorizon(hpa *autoscalingv1.HPAModifier) time.Duration {
+	if hpa.Spec.PredictionWindow <= 0 {
+		return DefaultPredictionWindow
 	}
-	return &result, nil
+	return time.Duration(hpa.Spec.PredictionWindow) * time.Second
 }
 
-// CalculateDesiredReplicas 计算期望的副本数
-func (s *ScalingManager) CalculateDesiredReplicas(hpa *autoscalingv1.HPAModifier, cpuUsage, memoryUsage float64) (int32, float64, error) {
-	// 获取 CPU 和内存的预测结果
-	cpuPrediction, err := s.queryPrediction("cpu")
+// predictionStep 返回预测序列的步长
+func (s *ScalingManager) predictionStep() time.Duration {
+	if s.PredictionStep <= 0 {
+		return DefaultPredictionStep
+	}
+	return s.PredictionStep
+}
+
+// queryPrediction 从预测服务获取目标工作负载在 horizon 内的预测结果
+func (s *ScalingManager) queryPrediction(ctx context.Context, hpa *autoscalingv1.HPAModifier, metric string, horizon time.Duration) (*PredictionResponse, error) {
+	step := s.predictionStep()
+
+	result, err := s.Predictor.Predict(ctx, s.PredictorURL, predictor.Request{
+		Target:    metric,
+		Namespace: hpa.Namespace,
+		Workload:  hpa.Spec.TargetRef.Name,
+		Kind:      hpa.Spec.TargetRef.Kind,
+		Horizon:   horizon,
+		Step:      step,
+	})
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to get CPU prediction: %v", err)
+		return nil, err
+	}
+	if err := validatePrediction(result, horizon, step); err != nil {
+		return nil, &predictor.Error{
+			Reason:   predictor.ReasonInvalidResponse,
+			Endpoint: s.PredictorURL,
+			Err:      fmt.Errorf("invalid %s prediction for %s/%s: %v", metric, hpa.Namespace, hpa.Spec.TargetRef.Name, err),
+		}
+	}
+	return result, nil
+}
+
+// validatePrediction 校验预测结果与请求的时间窗口一致，并丢弃窗口之外的预测点
+func validatePrediction(result *PredictionResponse, horizon, step time.Duration) error {
+	if len(result.Values) == 0 {
+		return fmt.Errorf("prediction contains no values")
+	}
+	if result.Horizon != 0 && time.Duration(result.Horizon)*time.Second != horizon {
+		return fmt.Errorf("prediction horizon %ds does not match requested %ds", result.Horizon, int64(horizon/time.Second))
+	}
+
+	points := int(math.Ceil(float64(horizon) / float64(step)))
+	if points < 1 {
+		points = 1
+	}
+	if len(result.Values) > points {
+		result.Values = result.Values[:points]
+	}
+	return nil
+}
+
+// workloadKey 返回工作负载在历史数据中的唯一标识
+func workloadKey(hpa *autoscalingv1.HPAModifier) string {
+	return fmt.Sprintf("%s/%s", hpa.Namespace, hpa.Spec.TargetRef.Name)
+}
+
+// trackWorkload 记录 HPAModifier 当前指向的工作负载，TargetRef 变化时清理旧工作负载的数据
+func (s *ScalingManager) trackWorkload(hpa *autoscalingv1.HPAModifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.workloads == nil {
+		s.workloads = make(map[string]string)
+	}
+	name := fmt.Sprintf("%s/%s", hpa.Namespace, hpa.Name)
+	key := workloadKey(hpa)
+	if previous, exists := s.workloads[name]; exists && previous != key {
+		s.forgetWorkload(previous)
+	}
+	s.workloads[name] = key
+}
+
+// Forget 在 HPAModifier 删除后恢复它修改过的原生 HPA，并清理其工作负载的历史数据、伸缩记录、伸缩决策和持久化的快照
+func (s *ScalingManager) Forget(ctx context.Context, namespace, name string) error {
+	if err := s.restoreNativeHPAs(ctx, namespace, name); err != nil {
+		return err
+	}
+	if s.Decisions != nil {
+		s.Decisions.Forget(namespace, name)
+	}
+	s.mu.Lock()
+	hpaName := fmt.Sprintf("%s/%s", namespace, name)
+	key, exists := s.workloads[hpaName]
+	if exists {
+		delete(s.workloads, hpaName)
+		s.forgetWorkload(key)
+	}
+	s.mu.Unlock()
+
+	if !exists || s.HistoryPersister == nil {
+		return nil
+	}
+	return s.HistoryPersister.Delete(ctx, key)
+}
+
+// LastDecision 返回 HPAModifier 最近一次的伸缩决策，没有时返回 nil
+func (s *ScalingManager) LastDecision(namespace, name string) *ScalingDecision {
+	if s.Decisions == nil {
+		return nil
+	}
+	return s.Decisions.Get(namespace, name)
+}
+
+// forgetWorkload 删除工作负载的历史数据和伸缩记录
+func (s *ScalingManager) forgetWorkload(key string) {
+	delete(s.backfilled, key)
+	delete(s.startups, key)
+	s.strategyFactory.Forget(key)
+	s.behavior.forget(key)
+}
+
+// historyKey 返回指标历史数据的键，CPU 沿用模式分析使用的工作负载标识
+func historyKey(hpa *autoscalingv1.HPAModifier, metric string) string {
+	if metric == "cpu" {
+		return workloadKey(hpa)
+	}
+	return workloadKey(hpa) + "#" + metric
+}
+
+// predict 获取指标在预测窗口内的预测结果，按 spec.forecaster 选择外部预测服务或内置预测器
+func (s *ScalingManager) predict(ctx context.Context, hpa *autoscalingv1.HPAModifier, metric string, currentValue float64) (*PredictionResponse, error) {
+	return s.predictOver(ctx, hpa, metric, currentValue, predictionHorizon(hpa))
+}
+
+// predictOver 获取指标在 horizon 内的预测结果，第 i 个预测点对应预测时间之后第 i+1 个步长
+func (s *ScalingManager) predictOver(ctx context.Context, hpa *autoscalingv1.HPAModifier, metric string, currentValue float64,
+	horizon time.Duration) (*PredictionResponse, error) {
+	forecaster := NewForecaster(hpa.Spec.Forecaster)
+	if forecaster == nil {
+		return s.queryPrediction(ctx, hpa, metric, horizon)
 	}
 
-	memPrediction, err := s.queryPrediction("memory")
+	history := s.strategyFactory.History(historyKey(hpa, metric))
+	if len(history) == 0 {
+		history = []float64{currentValue}
+	}
+	// 内置预测器的步长与历史数据的采样间隔一致
+	steps := int(math.Ceil(float64(horizon) / float64(s.strategyFactory.SampleInterval())))
+	values, err := forecaster.Forecast(history, steps)
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to get memory prediction: %v", err)
+		return nil, fmt.Errorf("%s forecaster failed: %v", forecaster.Name(), err)
 	}
+	return &PredictionResponse{
+		Values:    values,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}, nil
+}
 
-	// 计算最大预测负载
-	var maxCPULoad, maxMemLoad float64
-	for _, v := range cpuPrediction.Values {
-		if v > maxCPULoad {
-			maxCPULoad = v
-		}
+// predictPeak 获取指标在预测窗口内的峰值
+func (s *ScalingManager) predictPeak(ctx context.Context, hpa *autoscalingv1.HPAModifier, metric string, currentValue float64) (float64, error) {
+	prediction, err := s.predict(ctx, hpa, metric, currentValue)
+	if err != nil {
+		return 0, err
 	}
-	for _, v := range memPrediction.Values {
-		if v > maxMemLoad {
-			maxMemLoad = v
+	var peak float64
+	for _, v := range prediction.Values {
+		if v > peak {
+			peak = v
 		}
 	}
+	return peak, nil
+}
+
+// predictPeakLoad 获取 CPU 和内存在预测窗口内的峰值
+func (s *ScalingManager) predictPeakLoad(ctx context.Context, hpa *autoscalingv1.HPAModifier, cpuUsage, memoryUsage float64) (float64, float64, error) {
+	maxCPULoad, err := s.predictPeak(ctx, hpa, "cpu", cpuUsage)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get CPU prediction: %w", err)
+	}
+
+	maxMemLoad, err := s.predictPeak(ctx, hpa, "memory", memoryUsage)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get memory prediction: %w", err)
+	}
+	return maxCPULoad, maxMemLoad, nil
+}
+
+// CalculateDesiredReplicas 计算期望的副本数
+func (s *ScalingManager) CalculateDesiredReplicas(ctx context.Context, hpa *autoscalingv1.HPAModifier, cpuUsage, memoryUsage float64) (int32, float64, error) {
+	return s.calculateDesiredReplicas(ctx, hpa, nil, cpuUsage, memoryUsage, nil)
+}
+
+// calculateDesiredReplicas 计算期望的副本数，并把指标、建议和修改记录到 decision
+func (s *ScalingManager) calculateDesiredReplicas(ctx context.Context, hpa *autoscalingv1.HPAModifier, samples *podSamples,
+	cpuUsage, memoryUsage float64, decision *ScalingDecision) (int32, float64, error) {
+	// 设置了 spec.metrics 时按每个指标的建议计算
+	if len(hpa.Spec.Metrics) > 0 {
+		return s.calculateMetricSpecReplicas(ctx, hpa, samples, cpuUsage, memoryUsage, decision)
+	}
+
+	// webhook 未启用时阈值可能为 0，避免除零
+	if hpa.Spec.CPUThreshold <= 0 || hpa.Spec.MemoryThreshold <= 0 {
+		return 0, 0, fmt.Errorf("cpuThreshold and memoryThreshold must be greater than 0")
+	}
+
+	// 获取 CPU 和内存的预测峰值，预测不可用时退化为基于实时用量的被动伸缩，
+	// 下一次调谐会重新尝试预测，恢复后自动切回预测模式
+	maxCPULoad, maxMemLoad, err := s.predictPeakLoad(ctx, hpa, cpuUsage, memoryUsage)
+	if err != nil {
+		maxCPULoad, maxMemLoad = cpuUsage, memoryUsage
+	}
+	setPredictionConditions(hpa, err)
+	decision.setForecastError(err)
 
 	// 计算 CPU 和内存的负载比率
 	cpuRatio := maxCPULoad / hpa.Spec.CPUThreshold
 	memRatio := maxMemLoad / hpa.Spec.MemoryThreshold
+	decision.addMetric(MetricInput{Name: "cpu", Current: cpuUsage, Peak: maxCPULoad, Target: hpa.Spec.CPUThreshold})
+	decision.addMetric(MetricInput{Name: "memory", Current: memoryUsage, Peak: maxMemLoad, Target: hpa.Spec.MemoryThreshold})
+	decision.propose("cpu", cpuRatio, replicasForRatio(hpa, cpuRatio))
+	decision.propose("memory", memRatio, replicasForRatio(hpa, memRatio))
 
 	// 使用较大的比率作为伸缩依据
 	maxRatio := math.Max(cpuRatio, memRatio)
 
-	// 计算期望的副本数
-	currentReplicas := hpa.Status.CurrentReplicas
-	desiredReplicas := int32(math.Ceil(float64(currentReplicas) * maxRatio))
+	// 计算期望的副本数，负载比率落在容差带内时保持当前副本数
+	proposed := replicasForRatio(hpa, maxRatio)
+	desiredReplicas, suppressed := applyTolerance(hpa, maxRatio, proposed)
+	countSuppressedDecision(hpa, suppressed, desiredReplicas)
+	if suppressed {
+		decision.adjust(StageTolerance, proposed, desiredReplicas, fmt.Sprintf("load ratio %.2f is within the tolerance band", maxRatio))
+	}
 
 	// 确保在最小和最大副本数范围内
+	desiredReplicas = applyReplicaLimits(hpa, desiredReplicas, decision)
+
+	return desiredReplicas, maxRatio, nil
+}
+
+// setPredictionConditions 根据预测结果设置 PredictionAvailable 和 PredictorUnavailable 条件
+func setPredictionConditions(hpa *autoscalingv1.HPAModifier, err error) {
+	if err == nil {
+		setCondition(hpa, autoscalingv1.ConditionPredictionAvailable, metav1.ConditionTrue,
+			autoscalingv1.ReasonPredictionSucceeded, "load prediction is available")
+		setCondition(hpa, autoscalingv1.ConditionPredictorUnavailable, metav1.ConditionFalse,
+			autoscalingv1.ReasonPredictionSucceeded, "scaling on predicted load")
+		return
+	}
+	// 预测服务的错误类型作为条件原因，便于区分超时、熔断和异常响应
+	reason := predictor.ReasonOf(err)
+	if reason == "" {
+		reason = autoscalingv1.ReasonPredictionFailed
+	}
+	setCondition(hpa, autoscalingv1.ConditionPredictionAvailable, metav1.ConditionFalse, reason, err.Error())
+	setCondition(hpa, autoscalingv1.ConditionPredictorUnavailable, metav1.ConditionTrue,
+		reason, fmt.Sprintf("scaling reactively on live usage: %v", err))
+}
+
+// applyReplicaLimits 将期望副本数限制在 MinReplicas 和 MaxReplicas 之间，并记录 ScalingLimited 条件
+func applyReplicaLimits(hpa *autoscalingv1.HPAModifier, desiredReplicas int32, decision *ScalingDecision) int32 {
 	if desiredReplicas < hpa.Spec.MinReplicas {
-		desiredReplicas = hpa.Spec.MinReplicas
+		message := fmt.Sprintf("desired replica count %d is below minReplicas %d", desiredReplicas, hpa.Spec.MinReplicas)
+		setCondition(hpa, autoscalingv1.ConditionScalingLimited, metav1.ConditionTrue, autoscalingv1.ReasonTooFewReplicas, message)
+		decision.adjust(StageReplicaLimits, desiredReplicas, hpa.Spec.MinReplicas, message)
+		return hpa.Spec.MinReplicas
 	}
 	if desiredReplicas > hpa.Spec.MaxReplicas {
-		desiredReplicas = hpa.Spec.MaxReplicas
+		message := fmt.Sprintf("desired replica count %d is above maxReplicas %d", desiredReplicas, hpa.Spec.MaxReplicas)
+		setCondition(hpa, autoscalingv1.ConditionScalingLimited, metav1.ConditionTrue, autoscalingv1.ReasonTooManyReplicas, message)
+		decision.adjust(StageReplicaLimits, desiredReplicas, hpa.Spec.MaxReplicas, message)
+		return hpa.Spec.MaxReplicas
 	}
-
-	return desiredReplicas, maxRatio, nil
+	setCondition(hpa, autoscalingv1.ConditionScalingLimited, metav1.ConditionFalse, autoscalingv1.ReasonDesiredWithinRange,
+		"desired replica count is within the acceptable range")
+	return desiredReplicas
 }
 
-// ScaleWorkload 执行工作负载伸缩
-func (s *ScalingManager) ScaleWorkload(ctx context.Context, hpa *autoscalingv1.HPAModifier) error {
-	// 收集当前指标
-	cpuUsage, memoryUsage, err := s.CollectMetrics(ctx, hpa)
+// ScaleWorkload 执行工作负载伸缩，本次的伸缩决策保存在 Decisions 中
+func (s *ScalingManager) ScaleWorkload(ctx context.Context, hpa *autoscalingv1.HPAModifier) (err error) {
+	decision := newScalingDecision(hpa, time.Now())
+	// 根据本次调谐的结果设置 Ready 条件并保存决策
+	defer func() {
+		if err != nil {
+			decision.fail(err)
+		}
+		if s.Decisions != nil {
+			s.Decisions.Record(decision)
+		}
+		if err != nil {
+			setCondition(hpa, autoscalingv1.ConditionReady, metav1.ConditionFalse, autoscalingv1.ReasonReconcileFailed, err.Error())
+		} else {
+			setCondition(hpa, autoscalingv1.ConditionReady, metav1.ConditionTrue, autoscalingv1.ReasonReconciled,
+				"the HPAModifier was reconciled successfully")
+		}
+	}()
+
+	// 每次调谐只读取一次目标的 /scale 子资源，标签选择器、实时副本数和副本数的更新都基于它
+	scale, gr, err := s.getScale(ctx, hpa)
 	if err != nil {
-		return fmt.Errorf("failed to collect metrics: %v", err)
+		setCondition(hpa, autoscalingv1.ConditionAbleToScale, metav1.ConditionFalse,
+			conditionReason(err, autoscalingv1.ReasonFailedGetScale), err.Error())
+		return fmt.Errorf("failed to get current replicas: %w", err)
 	}
 
-	// 获取工作负载的唯一标识
-	workloadKey := fmt.Sprintf("%s/%s", hpa.Namespace, hpa.Spec.TargetRef.Name)
-
-	// 获取当前工作负载的策略
-	strategy := s.strategyFactory.GetStrategy(workloadKey, cpuUsage)
-
-	// 计算期望副本数
-	desiredReplicas, loadRatio, err := s.CalculateDesiredReplicas(hpa, cpuUsage, memoryUsage)
+	// 收集当前指标
+	previousSample := hpa.Status.LastSampleTime
+	var cpuUsage, memoryUsage float64
+	var samples *podSamples
+	selector, err := targetSelector(hpa, scale)
+	if err == nil {
+		samples, err = s.collectPodSamples(ctx, hpa, selector)
+	}
+	if err == nil {
+		cpuUsage, memoryUsage, err = samples.resourceUsage(hpa, usesUtilization(hpa))
+	}
+	if err != nil {
+		var staleErr *StaleMetricsError
+		if errors.As(err, &staleErr) {
+			setFreshnessCondition(hpa, previousSample)
+		}
+		setCondition(hpa, autoscalingv1.ConditionMetricsAvailable, metav1.ConditionFalse,
+			conditionReason(err, autoscalingv1.ReasonFailedGetMetrics), err.Error())
+		return fmt.Errorf("failed to collect metrics: %w", err)
+	}
+	usage := fmt.Sprintf("cpu %.3f cores, memory %.3f GiB per pod", cpuUsage, memoryUsage)
+	if usesUtilization(hpa) {
+		usage = fmt.Sprintf("cpu %.1f%%, memory %.1f%% of requests", cpuUsage*100, memoryUsage*100)
+	}
+	if fn := aggregationFunction(hpa); fn != autoscalingv1.AggregationMean {
+		usage += fmt.Sprintf(" (%s across pods)", fn)
+	}
+	setCondition(hpa, autoscalingv1.ConditionMetricsAvailable, metav1.ConditionTrue, autoscalingv1.ReasonMetricsCollected, usage)
+	setFreshnessCondition(hpa, previousSample)
+
+	// 识别工作负载模式并获取对应的策略，指标来源未更新时不重复写入历史数据
+	s.trackWorkload(hpa)
+	s.backfillHistory(ctx, hpa, selector)
+	var pattern WorkloadPattern
+	if isDuplicateSample(hpa) {
+		pattern = s.strategyFactory.CurrentPattern(workloadKey(hpa))
+	} else {
+		pattern = s.strategyFactory.DetectPattern(workloadKey(hpa), cpuUsage)
+		// 记录内存历史，供内置预测器使用
+		s.strategyFactory.RecordSample(historyKey(hpa, "memory"), memoryUsage)
+	}
+	strategy := s.strategyFactory.StrategyFor(pattern)
+	hpa.Status.WorkloadPattern = pattern.String()
+	hpa.Status.Strategy = strategy.Name()
+	decision.Inputs.Pattern = hpa.Status.WorkloadPattern
+	decision.Inputs.Strategy = hpa.Status.Strategy
+
+	// 以目标的实时副本数为基数计算期望副本数
+	currentReplicas := observeReplicas(hpa, scale)
+	decision.Inputs.CurrentReplicas = currentReplicas
+	decision.Inputs.ReadyReplicas = hpa.Status.ReadyReplicas
+	decision.Inputs.StartingReplicas = hpa.Status.StartingReplicas
+	desiredReplicas, loadRatio, err := s.calculateDesiredReplicas(ctx, hpa, samples, cpuUsage, memoryUsage, decision)
 	if err != nil {
 		return fmt.Errorf("failed to calculate desired replicas: %v", err)
 	}
 
-	// 检查是否需要预热
-	if strategy.ShouldPreWarm() {
-		// 获取预测结果
-		cpuPrediction, err := s.queryPrediction("cpu")
+	// 按预测的时间线预热：在预测峰值到来前提前 Pod 启动耗时扩容
+	preWarmReplicas := s.planPreWarm(ctx, hpa, strategy, cpuUsage, desiredReplicas, time.Now())
+	decision.Inputs.Forecast.PreWarm = hpa.Status.NextPreWarm
+	if preWarmReplicas > desiredReplicas {
+		decision.propose("preWarm", 0, preWarmReplicas)
+		decision.adjust(StagePreWarm, desiredReplicas, preWarmReplicas, "scaling up ahead of the predicted peak")
+		desiredReplicas = applyReplicaLimits(hpa, preWarmReplicas, decision)
+	}
+	// 目标已被原生 HPA 管理时不直接修改副本数
+	nativeHPA, err := s.findNativeHPA(ctx, hpa)
+	if err != nil {
+		return err
+	}
+	if nativeHPA != nil {
+		hpa.Status.DesiredReplicas = desiredReplicas
+		hpa.Status.PredictedLoad = loadRatio
+		changed, err := s.coordinateNativeHPA(ctx, hpa, nativeHPA, desiredReplicas)
 		if err != nil {
-			return fmt.Errorf("failed to get CPU prediction: %v", err)
+			return err
 		}
-
-		// 如果预测到未来负载会超过阈值，提前扩容
-		if len(cpuPrediction.Values) > 0 {
-			maxPredictedLoad := 0.0
-			for _, v := range cpuPrediction.Values {
-				if v > maxPredictedLoad {
-					maxPredictedLoad = v
-				}
-			}
-
-			if maxPredictedLoad > strategy.GetScalingThreshold() {
-				// 提前扩容到预测需要的副本数
-				predictedReplicas := int32(math.Ceil(float64(hpa.Spec.MinReplicas) * maxPredictedLoad))
-				if predictedReplicas > desiredReplicas {
-					desiredReplicas = predictedReplicas
-				}
-			}
+		decision.Changed = changed
+		if hpa.Spec.NativeHPA == autoscalingv1.NativeHPAModify {
+			decision.finish(hpa, ActionModifyNativeHPA)
+		} else {
+			decision.finish(hpa, ActionSkipped)
 		}
+		return nil
 	}
+	setCondition(hpa, autoscalingv1.ConditionNativeHPAConflict, metav1.ConditionFalse, autoscalingv1.ReasonNoNativeHPA,
+		"no HorizontalPodAutoscaler targets the workload")
 
-	// 获取当前副本数
-	currentReplicas, err := s.getCurrentReplicas(ctx, hpa)
-	if err != nil {
-		return fmt.Errorf("failed to get current replicas: %v", err)
+	// 应用 behavior 的稳定窗口和速率策略
+	var stabilizedReason string
+	if hpa.Spec.Behavior != nil {
+		desiredReplicas, stabilizedReason = s.applyBehavior(hpa, currentReplicas, desiredReplicas, decision)
 	}
+	hpa.Status.DesiredReplicas = desiredReplicas
 
-	// 检查是否需要等待延迟时间
-	if currentReplicas != desiredReplicas {
+	// 检查是否需要等待延迟时间，设置 behavior 时由稳定窗口和速率策略控制节奏
+	if currentReplicas != desiredReplicas && hpa.Spec.Behavior == nil {
 		// 获取上次伸缩时间
 		lastScaledTime := hpa.Status.LastScaledTime
 		if lastScaledTime != nil {
 			// 检查是否已经过了延迟时间
 			if time.Since(lastScaledTime.Time) < strategy.GetScalingDelay() {
+				message := fmt.Sprintf("waiting for the %s strategy delay of %s since the last scale", strategy.Name(), strategy.GetScalingDelay())
+				setCondition(hpa, autoscalingv1.ConditionAbleToScale, metav1.ConditionFalse, autoscalingv1.ReasonBackoff, message)
+				decision.adjust(StageScalingDelay, desiredReplicas, currentReplicas, message)
+				decision.finish(hpa, ActionDelayed)
 				return nil // 等待延迟时间
 			}
 		}
 	}
 
 	// 更新工作负载的副本数
-	if err := s.updateReplicas(ctx, hpa, desiredReplicas); err != nil {
-		return fmt.Errorf("failed to update replicas: %v", err)
+	if err := s.updateReplicas(ctx, hpa, scale, gr, desiredReplicas); err != nil {
+		setCondition(hpa, autoscalingv1.ConditionAbleToScale, metav1.ConditionFalse,
+			conditionReason(err, autoscalingv1.ReasonFailedUpdateScale), err.Error())
+		return fmt.Errorf("failed to update replicas: %w", err)
+	}
+	s.recordScale(hpa, currentReplicas, desiredReplicas)
+	if currentReplicas != desiredReplicas {
+		setCondition(hpa, autoscalingv1.ConditionAbleToScale, metav1.ConditionTrue, autoscalingv1.ReasonSucceededRescale,
+			fmt.Sprintf("scaled from %d to %d replicas", currentReplicas, desiredReplicas))
+	} else if stabilizedReason != "" {
+		setCondition(hpa, autoscalingv1.ConditionAbleToScale, metav1.ConditionTrue, stabilizedReason,
+			"recent recommendations differ from the current one, holding the stabilized replica count")
+	} else {
+		setCondition(hpa, autoscalingv1.ConditionAbleToScale, metav1.ConditionTrue, autoscalingv1.ReasonReadyForNewScale,
+			"recommended size matches current size")
 	}
 
 	// 更新 HPA 状态
 	hpa.Status.LastScaledTime = &metav1.Time{Time: time.Now()}
 	hpa.Status.CurrentReplicas = desiredReplicas
+	hpa.Status.LastAppliedReplicas = desiredReplicas
 	hpa.Status.PredictedLoad = loadRatio
 
-	return nil
-}
-
-// getCurrentReplicas 获取当前副本数
-func (s *ScalingManager) getCurrentReplicas(ctx context.Context, hpa *autoscalingv1.HPAModifier) (int32, error) {
-	deployment, err := s.KubeClient.AppsV1().Deployments(hpa.Namespace).Get(ctx, hpa.Spec.TargetRef.Name, metav1.GetOptions{})
-	if err != nil {
-		return 0, err
+	decision.Changed = desiredReplicas != currentReplicas
+	switch {
+	case desiredReplicas > currentReplicas:
+		decision.finish(hpa, ActionScaleUp)
+	case desiredReplicas < currentReplicas:
+		decision.finish(hpa, ActionScaleDown)
+	default:
+		decision.finish(hpa, ActionNone)
 	}
-	return *deployment.Spec.Replicas, nil
+	return nil
 }
 
-// updateReplicas 更新工作负载的副本数
-func (s *ScalingManager) updateReplicas(ctx context.Context, hpa *autoscalingv1.HPAModifier, desiredReplicas int32) error {
-	scale, err := s.KubeClient.AppsV1().Deployments(hpa.Namespace).GetScale(ctx, hpa.Spec.TargetRef.Name, metav1.GetOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to get scale subresource: %v", err)
-	}
-
+// updateReplicas 基于本次调谐读取的 /scale 子资源更新工作负载的副本数，期间目标被修改时更新因冲突失败
+func (s *ScalingManager) updateReplicas(ctx context.Context, hpa *autoscalingv1.HPAModifier, scale *k8sautoscalingv1.Scale,
+	gr schema.GroupResource, desiredReplicas int32) error {
 	scale.Spec.Replicas = desiredReplicas
-	_, err = s.KubeClient.AppsV1().Deployments(hpa.Namespace).UpdateScale(ctx, hpa.Spec.TargetRef.Name, scale, metav1.UpdateOptions{})
+	_, err := s.ScaleClient.Scales(hpa.Namespace).Update(ctx, gr, scale, metav1.UpdateOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to update scale subresource: %v", err)
 	}
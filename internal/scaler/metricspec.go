@@ -0,0 +1,339 @@
+package scaler
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+)
+
+// bytesPerGiB 内存指标在历史数据和预测中以 GiB 为单位
+const bytesPerGiB = 1024 * 1024 * 1024
+
+// metricProposal 一个指标给出的副本数建议
+type metricProposal struct {
+	metric   string  // 指标在历史数据中的名称
+	replicas int32   // 建议的副本数
+	ratio    float64 // 预测峰值与当前副本数能承载的目标值之比
+}
+
+// calculateMetricSpecReplicas 对 spec.metrics 中的每个指标取当前值、预测峰值并计算副本数建议，取最大的建议。
+// 与 HPA 相同，部分指标获取失败时仍按其余指标计算，但不缩容
+func (s *ScalingManager) calculateMetricSpecReplicas(ctx context.Context, hpa *autoscalingv1.HPAModifier, samples *podSamples,
+	cpuUsage, memoryUsage float64, decision *ScalingDecision) (int32, float64, error) {
+	currentReplicas := hpa.Status.CurrentReplicas
+
+	var best *metricProposal
+	var metricErrs []string
+	var predictionErr error
+	var suppressed bool
+	for i, metric := range hpa.Spec.Metrics {
+		name := metricName(hpa, metric)
+		value, err := s.metricValue(ctx, hpa, samples, metric, cpuUsage, memoryUsage)
+		if err != nil {
+			metricErrs = append(metricErrs, fmt.Sprintf("metrics[%d] %s: %v", i, name, err))
+			decision.addMetric(MetricInput{Name: name, Error: err.Error()})
+			continue
+		}
+		// 与 spec.thresholdMode 单位相同的 CPU 和内存历史已在收集指标时记录，Pod 用量样本重复时不记录
+		if metric.Type != autoscalingv2.ResourceMetricSourceType ||
+			(name != string(metric.Resource.Name) && !isDuplicateSample(hpa)) {
+			s.strategyFactory.RecordSample(historyKey(hpa, name), value)
+		}
+
+		// 预测不可用时按当前值计算
+		peak, err := s.predictPeak(ctx, hpa, name, value)
+		if err != nil {
+			if predictionErr == nil {
+				predictionErr = fmt.Errorf("failed to get %s prediction: %w", name, err)
+			}
+			peak = value
+		}
+
+		input := MetricInput{Name: name, Current: value, Peak: peak}
+		proposal, err := proposeReplicas(hpa, metric, name, peak)
+		if err != nil {
+			metricErrs = append(metricErrs, fmt.Sprintf("metrics[%d] %s: %v", i, name, err))
+			input.Error = err.Error()
+			decision.addMetric(input)
+			continue
+		}
+		_, input.Target, _ = metricTarget(metric)
+		decision.addMetric(input)
+		decision.propose(name, proposal.ratio, proposal.replicas)
+
+		// 与 HPA 相同，每个指标单独应用容差带
+		var withinBand bool
+		proposed := proposal.replicas
+		proposal.replicas, withinBand = applyTolerance(hpa, proposal.ratio, proposal.replicas)
+		suppressed = suppressed || withinBand
+		decision.adjust(StageTolerance, proposed, proposal.replicas,
+			fmt.Sprintf("%s load ratio %.2f is within the tolerance band", name, proposal.ratio))
+		if best == nil || proposal.replicas > best.replicas {
+			best = proposal
+		}
+	}
+
+	if best == nil {
+		return 0, 0, fmt.Errorf("no metric produced a replica proposal: %s", strings.Join(metricErrs, "; "))
+	}
+	setPredictionConditions(hpa, predictionErr)
+	decision.setForecastError(predictionErr)
+
+	desiredReplicas := best.replicas
+	countSuppressedDecision(hpa, suppressed, desiredReplicas)
+	if len(metricErrs) > 0 {
+		setCondition(hpa, autoscalingv1.ConditionMetricsAvailable, metav1.ConditionFalse, autoscalingv1.ReasonFailedGetMetrics,
+			fmt.Sprintf("scaling on the remaining metrics without scaling down: %s", strings.Join(metricErrs, "; ")))
+		if desiredReplicas < currentReplicas {
+			decision.adjust(StageMissingMetrics, desiredReplicas, currentReplicas, "some metrics are unavailable, not scaling down")
+			desiredReplicas = currentReplicas
+		}
+	}
+	return applyReplicaLimits(hpa, desiredReplicas, decision), best.ratio, nil
+}
+
+// metricName 返回指标在历史数据和预测请求中使用的名称。
+// 目标类型与 spec.thresholdMode 单位相同的 CPU 和内存沿用已有的序列，否则使用单独的序列
+func metricName(hpa *autoscalingv1.HPAModifier, metric autoscalingv2.MetricSpec) string {
+	switch metric.Type {
+	case autoscalingv2.ResourceMetricSourceType:
+		if metric.Resource != nil {
+			name := string(metric.Resource.Name)
+			utilization := metric.Resource.Target.Type == autoscalingv2.UtilizationMetricType
+			if utilization == usesUtilization(hpa) {
+				return name
+			}
+			if utilization {
+				return name + "/utilization"
+			}
+			return name + "/average"
+		}
+	case autoscalingv2.PodsMetricSourceType:
+		if metric.Pods != nil {
+			return "pods/" + metric.Pods.Metric.Name
+		}
+	case autoscalingv2.ObjectMetricSourceType:
+		if metric.Object != nil {
+			object := metric.Object.DescribedObject
+			return fmt.Sprintf("object/%s/%s/%s", strings.ToLower(object.Kind), object.Name, metric.Object.Metric.Name)
+		}
+	case autoscalingv2.ExternalMetricSourceType:
+		if metric.External != nil {
+			return "external/" + metric.External.Metric.Name
+		}
+	}
+	return strings.ToLower(string(metric.Type))
+}
+
+// usesUtilization CollectMetrics 是否返回相对 requests 的利用率
+func usesUtilization(hpa *autoscalingv1.HPAModifier) bool {
+	return hpa.Spec.ThresholdMode == autoscalingv1.ThresholdModeUtilization
+}
+
+// podSamplesFor 直接调用 CalculateDesiredReplicas 时没有本次调谐收集的 Pod 指标，重新收集
+func (s *ScalingManager) podSamplesFor(ctx context.Context, hpa *autoscalingv1.HPAModifier) (*podSamples, error) {
+	selector, err := s.resolveSelector(ctx, hpa)
+	if err != nil {
+		return nil, err
+	}
+	return s.collectPodSamples(ctx, hpa, selector)
+}
+
+// podSelector 返回本次调谐解析的目标 Pod 选择器，没有时重新解析
+func (s *ScalingManager) podSelector(ctx context.Context, hpa *autoscalingv1.HPAModifier, samples *podSamples) (labels.Selector, error) {
+	if samples != nil {
+		return samples.selector, nil
+	}
+	return s.resolveSelector(ctx, hpa)
+}
+
+// metricValue 读取指标的当前值：Resource 和 Pods 为每个 Pod 的平均值或利用率，Object 和 External 为总值。
+// samples 为本次调谐收集的目标 Pod 指标，直接调用 CalculateDesiredReplicas 时为空
+func (s *ScalingManager) metricValue(ctx context.Context, hpa *autoscalingv1.HPAModifier, samples *podSamples,
+	metric autoscalingv2.MetricSpec, cpuUsage, memoryUsage float64) (float64, error) {
+	switch metric.Type {
+	case autoscalingv2.ResourceMetricSourceType:
+		if metric.Resource == nil {
+			return 0, fmt.Errorf("resource is not set")
+		}
+		// 目标类型与 spec.thresholdMode 的单位不同时，从同一份 Pod 指标按目标类型计算
+		utilization := metric.Resource.Target.Type == autoscalingv2.UtilizationMetricType
+		if utilization != usesUtilization(hpa) {
+			var err error
+			if samples == nil {
+				if samples, err = s.podSamplesFor(ctx, hpa); err != nil {
+					return 0, err
+				}
+			}
+			if cpuUsage, memoryUsage, err = samples.resourceUsage(hpa, utilization); err != nil {
+				return 0, err
+			}
+		}
+		switch metric.Resource.Name {
+		case corev1.ResourceCPU:
+			return cpuUsage, nil
+		case corev1.ResourceMemory:
+			return memoryUsage, nil
+		}
+		return 0, fmt.Errorf("unsupported resource %q", metric.Resource.Name)
+
+	case autoscalingv2.PodsMetricSourceType:
+		if metric.Pods == nil {
+			return 0, fmt.Errorf("pods is not set")
+		}
+		if s.CustomMetrics == nil {
+			return 0, fmt.Errorf("the custom metrics API is not configured")
+		}
+		selector, err := s.podSelector(ctx, hpa, samples)
+		if err != nil {
+			return 0, err
+		}
+		metricSelector, err := metricLabelSelector(metric.Pods.Metric.Selector)
+		if err != nil {
+			return 0, err
+		}
+		values, err := s.CustomMetrics.NamespacedMetrics(hpa.Namespace).
+			GetForObjects(schema.GroupKind{Kind: "Pod"}, selector, metric.Pods.Metric.Name, metricSelector)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get pods metric %s: %v", metric.Pods.Metric.Name, err)
+		}
+		if len(values.Items) == 0 {
+			return 0, fmt.Errorf("no pods matching selector %q report metric %s", selector.String(), metric.Pods.Metric.Name)
+		}
+		var total float64
+		for _, item := range values.Items {
+			total += item.Value.AsApproximateFloat64()
+		}
+		return total / float64(len(values.Items)), nil
+
+	case autoscalingv2.ObjectMetricSourceType:
+		if metric.Object == nil {
+			return 0, fmt.Errorf("object is not set")
+		}
+		if s.CustomMetrics == nil {
+			return 0, fmt.Errorf("the custom metrics API is not configured")
+		}
+		object := metric.Object.DescribedObject
+		gv, err := schema.ParseGroupVersion(object.APIVersion)
+		if err != nil {
+			return 0, fmt.Errorf("invalid describedObject apiVersion %q: %v", object.APIVersion, err)
+		}
+		metricSelector, err := metricLabelSelector(metric.Object.Metric.Selector)
+		if err != nil {
+			return 0, err
+		}
+		value, err := s.CustomMetrics.NamespacedMetrics(hpa.Namespace).
+			GetForObject(schema.GroupKind{Group: gv.Group, Kind: object.Kind}, object.Name, metric.Object.Metric.Name, metricSelector)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get object metric %s of %s %s: %v", metric.Object.Metric.Name, object.Kind, object.Name, err)
+		}
+		return value.Value.AsApproximateFloat64(), nil
+
+	case autoscalingv2.ExternalMetricSourceType:
+		if metric.External == nil {
+			return 0, fmt.Errorf("external is not set")
+		}
+		if s.ExternalMetrics == nil {
+			return 0, fmt.Errorf("the external metrics API is not configured")
+		}
+		metricSelector, err := metricLabelSelector(metric.External.Metric.Selector)
+		if err != nil {
+			return 0, err
+		}
+		values, err := s.ExternalMetrics.NamespacedMetrics(hpa.Namespace).List(metric.External.Metric.Name, metricSelector)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get external metric %s: %v", metric.External.Metric.Name, err)
+		}
+		if len(values.Items) == 0 {
+			return 0, fmt.Errorf("external metric %s returned no values", metric.External.Metric.Name)
+		}
+		var total float64
+		for _, item := range values.Items {
+			total += item.Value.AsApproximateFloat64()
+		}
+		return total, nil
+	}
+	return 0, fmt.Errorf("unsupported metric type %q", metric.Type)
+}
+
+// metricLabelSelector 转换指标的标签选择器，未设置时匹配全部
+func metricLabelSelector(selector *metav1.LabelSelector) (labels.Selector, error) {
+	if selector == nil {
+		return labels.Everything(), nil
+	}
+	parsed, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metric selector: %v", err)
+	}
+	return parsed, nil
+}
+
+// metricTarget 返回指标的目标，利用率换算为比例，内存的目标换算为 GiB，与指标值的单位一致
+func metricTarget(metric autoscalingv2.MetricSpec) (autoscalingv2.MetricTargetType, float64, error) {
+	var target autoscalingv2.MetricTarget
+	switch metric.Type {
+	case autoscalingv2.ResourceMetricSourceType:
+		target = metric.Resource.Target
+	case autoscalingv2.PodsMetricSourceType:
+		target = metric.Pods.Target
+	case autoscalingv2.ObjectMetricSourceType:
+		target = metric.Object.Target
+	case autoscalingv2.ExternalMetricSourceType:
+		target = metric.External.Target
+	}
+
+	var value float64
+	switch {
+	case target.Type == autoscalingv2.ValueMetricType && target.Value != nil:
+		value = target.Value.AsApproximateFloat64()
+	case target.Type == autoscalingv2.AverageValueMetricType && target.AverageValue != nil:
+		value = target.AverageValue.AsApproximateFloat64()
+		if metric.Type == autoscalingv2.ResourceMetricSourceType && metric.Resource.Name == corev1.ResourceMemory {
+			value /= bytesPerGiB
+		}
+	case target.Type == autoscalingv2.UtilizationMetricType && target.AverageUtilization != nil &&
+		metric.Type == autoscalingv2.ResourceMetricSourceType:
+		value = float64(*target.AverageUtilization) / 100
+	default:
+		return "", 0, fmt.Errorf("unsupported target type %q", target.Type)
+	}
+	if value <= 0 {
+		return "", 0, fmt.Errorf("target must be greater than 0")
+	}
+	return target.Type, value, nil
+}
+
+// proposeReplicas 按与 HPA 相同的规则将指标值换算为副本数：
+// 利用率、每个 Pod 的平均值和 Value 目标按比例缩放当前副本数，Object 和 External 的 AverageValue 目标用总值除以目标值
+func proposeReplicas(hpa *autoscalingv1.HPAModifier, metric autoscalingv2.MetricSpec, name string, value float64) (*metricProposal, error) {
+	targetType, target, err := metricTarget(metric)
+	if err != nil {
+		return nil, err
+	}
+
+	perPod := metric.Type == autoscalingv2.ResourceMetricSourceType || metric.Type == autoscalingv2.PodsMetricSourceType
+	if targetType == autoscalingv2.AverageValueMetricType && !perPod {
+		capacity := target * math.Max(float64(hpa.Status.CurrentReplicas), 1)
+		return &metricProposal{
+			metric:   name,
+			replicas: int32(math.Ceil(value / target)),
+			ratio:    value / capacity,
+		}, nil
+	}
+
+	ratio := value / target
+	return &metricProposal{
+		metric:   name,
+		replicas: replicasForRatio(hpa, ratio),
+		ratio:    ratio,
+	}, nil
+}
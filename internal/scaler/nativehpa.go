@@ -0,0 +1,174 @@
+package scaler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+)
+
+// findNativeHPA 查找 scaleTargetRef 与 TargetRef 指向同一工作负载的 autoscaling/v2 HPA，没有时返回 nil
+func (s *ScalingManager) findNativeHPA(ctx context.Context, hpa *autoscalingv1.HPAModifier) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	targetGVK, err := targetGroupVersionKind(hpa.Spec.TargetRef)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := s.KubeClient.AutoscalingV2().HorizontalPodAutoscalers(hpa.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list HorizontalPodAutoscalers in %s: %v", hpa.Namespace, err)
+	}
+	for i := range list.Items {
+		ref := list.Items[i].Spec.ScaleTargetRef
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			continue
+		}
+		if gv.Group == targetGVK.Group && ref.Kind == targetGVK.Kind && ref.Name == hpa.Spec.TargetRef.Name {
+			return &list.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// 修改原生 HPA 时记录的注解，HPAModifier 删除后据此恢复 HPA 原来的 minReplicas
+const (
+	// nativeHPAModifierAnnot 修改 HPA 的 HPAModifier 名称
+	nativeHPAModifierAnnot = "autoscaling.yemo.info/modified-by"
+	// nativeHPAMinReplicasAnnot HPA 原来的 minReplicas，原来未设置时为空字符串
+	nativeHPAMinReplicasAnnot = "autoscaling.yemo.info/original-min-replicas"
+)
+
+// coordinateNativeHPA 目标已被原生 HPA 管理时不直接修改副本数：
+// Modify 模式下把预测得到的期望副本数作为 HPA 的 minReplicas，只会高于 HPA 原来的 minReplicas，不超过 HPA 自身的 maxReplicas，
+// 第一次修改前在注解中保存原来的 minReplicas，期望副本数回落到原来的下限以内时恢复；否则记录冲突并跳过伸缩。返回是否修改了 HPA
+func (s *ScalingManager) coordinateNativeHPA(ctx context.Context, hpa *autoscalingv1.HPAModifier,
+	nativeHPA *autoscalingv2.HorizontalPodAutoscaler, desiredReplicas int32) (bool, error) {
+	if hpa.Spec.NativeHPA != autoscalingv1.NativeHPAModify {
+		// 从 Modify 切换回来时恢复 HPA 原来的下限
+		restored, err := s.restoreNativeHPA(ctx, nativeHPA)
+		if err != nil {
+			return false, err
+		}
+		message := fmt.Sprintf("HorizontalPodAutoscaler %q already scales %s %q, set spec.nativeHPA to Modify to adjust its minReplicas instead",
+			nativeHPA.Name, nativeHPA.Spec.ScaleTargetRef.Kind, nativeHPA.Spec.ScaleTargetRef.Name)
+		setCondition(hpa, autoscalingv1.ConditionNativeHPAConflict, metav1.ConditionTrue, autoscalingv1.ReasonNativeHPAFound, message)
+		setCondition(hpa, autoscalingv1.ConditionAbleToScale, metav1.ConditionFalse, autoscalingv1.ReasonNativeHPAFound, message)
+		return restored, nil
+	}
+
+	// 预测的期望副本数作为下限，HPA 仍可根据自身指标在用户设置的上限内继续扩容
+	original, err := originalMinReplicas(nativeHPA)
+	if err != nil {
+		return false, err
+	}
+	minReplicas := desiredReplicas
+	if minReplicas > nativeHPA.Spec.MaxReplicas {
+		minReplicas = nativeHPA.Spec.MaxReplicas
+	}
+	if minReplicas <= original {
+		// 不低于用户设置的下限，之前提高过时恢复
+		changed, err := s.restoreNativeHPA(ctx, nativeHPA)
+		if err != nil {
+			setCondition(hpa, autoscalingv1.ConditionAbleToScale, metav1.ConditionFalse, autoscalingv1.ReasonFailedUpdateScale, err.Error())
+			return false, err
+		}
+		message := fmt.Sprintf("HorizontalPodAutoscaler %q keeps its own minReplicas %d, which covers the desired %d replicas",
+			nativeHPA.Name, original, desiredReplicas)
+		setCondition(hpa, autoscalingv1.ConditionNativeHPAConflict, metav1.ConditionFalse, autoscalingv1.ReasonModifiedNativeHPA, message)
+		setCondition(hpa, autoscalingv1.ConditionAbleToScale, metav1.ConditionTrue, autoscalingv1.ReasonModifiedNativeHPA, message)
+		return changed, nil
+	}
+
+	changed := nativeHPA.Spec.MinReplicas == nil || *nativeHPA.Spec.MinReplicas != minReplicas
+	if changed {
+		updated := nativeHPA.DeepCopy()
+		if _, saved := updated.Annotations[nativeHPAMinReplicasAnnot]; !saved {
+			if updated.Annotations == nil {
+				updated.Annotations = make(map[string]string)
+			}
+			original := ""
+			if updated.Spec.MinReplicas != nil {
+				original = strconv.Itoa(int(*updated.Spec.MinReplicas))
+			}
+			updated.Annotations[nativeHPAMinReplicasAnnot] = original
+		}
+		updated.Annotations[nativeHPAModifierAnnot] = hpa.Name
+		updated.Spec.MinReplicas = &minReplicas
+		if _, err := s.KubeClient.AutoscalingV2().HorizontalPodAutoscalers(hpa.Namespace).Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+			setCondition(hpa, autoscalingv1.ConditionAbleToScale, metav1.ConditionFalse, autoscalingv1.ReasonFailedUpdateScale, err.Error())
+			return false, fmt.Errorf("failed to update HorizontalPodAutoscaler %q: %v", nativeHPA.Name, err)
+		}
+		hpa.Status.LastScaledTime = &metav1.Time{Time: time.Now()}
+	}
+
+	message := fmt.Sprintf("adjusting HorizontalPodAutoscaler %q to minReplicas %d", nativeHPA.Name, minReplicas)
+	setCondition(hpa, autoscalingv1.ConditionNativeHPAConflict, metav1.ConditionFalse, autoscalingv1.ReasonModifiedNativeHPA, message)
+	setCondition(hpa, autoscalingv1.ConditionAbleToScale, metav1.ConditionTrue, autoscalingv1.ReasonModifiedNativeHPA, message)
+	return changed, nil
+}
+
+// originalMinReplicas 返回 HPA 被修改前的 minReplicas：已保存时读取注解，否则为当前值，未设置时为 HPA 的默认值 1
+func originalMinReplicas(nativeHPA *autoscalingv2.HorizontalPodAutoscaler) (int32, error) {
+	if original, saved := nativeHPA.Annotations[nativeHPAMinReplicasAnnot]; saved {
+		if original == "" {
+			return 1, nil
+		}
+		value, err := strconv.ParseInt(original, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s annotation on HorizontalPodAutoscaler %q: %v", nativeHPAMinReplicasAnnot, nativeHPA.Name, err)
+		}
+		return int32(value), nil
+	}
+	if nativeHPA.Spec.MinReplicas != nil {
+		return *nativeHPA.Spec.MinReplicas, nil
+	}
+	return 1, nil
+}
+
+// restoreNativeHPA 恢复 HPA 被修改前的 minReplicas 并删除注解，返回是否恢复了 HPA，HPA 未被修改过时不做任何事
+func (s *ScalingManager) restoreNativeHPA(ctx context.Context, nativeHPA *autoscalingv2.HorizontalPodAutoscaler) (bool, error) {
+	original, saved := nativeHPA.Annotations[nativeHPAMinReplicasAnnot]
+	if !saved {
+		return false, nil
+	}
+	updated := nativeHPA.DeepCopy()
+	updated.Spec.MinReplicas = nil
+	if original != "" {
+		value, err := strconv.ParseInt(original, 10, 32)
+		if err != nil {
+			return false, fmt.Errorf("invalid %s annotation on HorizontalPodAutoscaler %q: %v", nativeHPAMinReplicasAnnot, nativeHPA.Name, err)
+		}
+		minReplicas := int32(value)
+		updated.Spec.MinReplicas = &minReplicas
+	}
+	delete(updated.Annotations, nativeHPAMinReplicasAnnot)
+	delete(updated.Annotations, nativeHPAModifierAnnot)
+	if _, err := s.KubeClient.AutoscalingV2().HorizontalPodAutoscalers(nativeHPA.Namespace).Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		return false, fmt.Errorf("failed to restore HorizontalPodAutoscaler %q: %v", nativeHPA.Name, err)
+	}
+	return true, nil
+}
+
+// restoreNativeHPAs 在 HPAModifier 删除后恢复它修改过的 HPA
+func (s *ScalingManager) restoreNativeHPAs(ctx context.Context, namespace, name string) error {
+	list, err := s.KubeClient.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list HorizontalPodAutoscalers in %s: %v", namespace, err)
+	}
+	for i := range list.Items {
+		if list.Items[i].Annotations[nativeHPAModifierAnnot] != name {
+			continue
+		}
+		if _, err := s.restoreNativeHPA(ctx, &list.Items[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
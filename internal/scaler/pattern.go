@@ -17,46 +17,60 @@ const (
 	PatternBurst
 )
 
+// String 返回模式名称
+func (p WorkloadPattern) String() string {
+	switch p {
+	case PatternStable:
+		return "Stable"
+	case PatternPeriodic:
+		return "Periodic"
+	case PatternBurst:
+		return "Burst"
+	default:
+		return "Unknown"
+	}
+}
+
 // PatternAnalyzer 分析工作负载模式
 type PatternAnalyzer struct {
-	// 历史数据窗口大小
-	historyWindow time.Duration
-	// 采样间隔
-	sampleInterval time.Duration
-	// 历史数据
-	historyData map[string][]float64
+	// 历史数据，按采样间隔重采样，保留 historyWindow 内的数据
+	history *HistoryStore
 }
 
 // NewPatternAnalyzer 创建新的模式分析器
 func NewPatternAnalyzer(historyWindow, sampleInterval time.Duration) *PatternAnalyzer {
 	return &PatternAnalyzer{
-		historyWindow:  historyWindow,
-		sampleInterval: sampleInterval,
-		historyData:    make(map[string][]float64),
+		history: NewHistoryStore(historyWindow, sampleInterval),
 	}
 }
 
 // AnalyzePattern 分析工作负载模式
 func (pa *PatternAnalyzer) AnalyzePattern(workloadKey string, currentValue float64) WorkloadPattern {
 	// 更新历史数据
-	if _, exists := pa.historyData[workloadKey]; !exists {
-		pa.historyData[workloadKey] = make([]float64, 0)
-	}
-	pa.historyData[workloadKey] = append(pa.historyData[workloadKey], currentValue)
-
-	// 保持历史数据在窗口范围内
-	windowSize := int(pa.historyWindow / pa.sampleInterval)
-	if len(pa.historyData[workloadKey]) > windowSize {
-		pa.historyData[workloadKey] = pa.historyData[workloadKey][len(pa.historyData[workloadKey])-windowSize:]
-	}
+	pa.AddSample(workloadKey, currentValue)
 
 	// 分析模式
 	return pa.determinePattern(workloadKey)
 }
 
+// AddSample 以当前时间记录一个样本，不做模式分析
+func (pa *PatternAnalyzer) AddSample(workloadKey string, value float64) {
+	pa.history.Add(workloadKey, time.Now(), value)
+}
+
+// History 返回工作负载按采样间隔重采样后的历史数据
+func (pa *PatternAnalyzer) History(workloadKey string) []float64 {
+	return pa.history.Values(workloadKey)
+}
+
+// Forget 删除工作负载的历史数据
+func (pa *PatternAnalyzer) Forget(workloadKey string) {
+	pa.history.DeleteWorkload(workloadKey)
+}
+
 // determinePattern 确定工作负载模式
 func (pa *PatternAnalyzer) determinePattern(workloadKey string) WorkloadPattern {
-	data := pa.historyData[workloadKey]
+	data := pa.history.Values(workloadKey)
 	if len(data) < 2 {
 		return PatternStable // 数据不足时默认为稳定型
 	}
@@ -110,6 +124,21 @@ func detectPeriodicity(data []float64) bool {
 	}
 
 	// 使用自相关函数检测周期性
+	autocorr := autocorrelation(data)
+
+	// 检查自相关函数是否有明显的周期性峰值
+	peakCount := 0
+	for i := 1; i < len(autocorr)-1; i++ {
+		if autocorr[i] > autocorr[i-1] && autocorr[i] > autocorr[i+1] && autocorr[i] > 0.5 {
+			peakCount++
+		}
+	}
+
+	return peakCount >= 2
+}
+
+// autocorrelation 计算 1 到 len(data)/2-1 阶的自相关系数，下标即滞后阶数
+func autocorrelation(data []float64) []float64 {
 	autocorr := make([]float64, len(data)/2)
 	mean := calculateMean(data)
 
@@ -127,16 +156,7 @@ func detectPeriodicity(data []float64) bool {
 		}
 		autocorr[lag] = numerator / denominator
 	}
-
-	// 检查自相关函数是否有明显的周期性峰值
-	peakCount := 0
-	for i := 1; i < len(autocorr)-1; i++ {
-		if autocorr[i] > autocorr[i-1] && autocorr[i] > autocorr[i+1] && autocorr[i] > 0.5 {
-			peakCount++
-		}
-	}
-
-	return peakCount >= 2
+	return autocorr
 }
 
 // detectBurst 检测突发性
@@ -0,0 +1,346 @@
+package scaler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+)
+
+// HistorySnapshot 一个工作负载全部历史序列的紧凑快照，序列已按采样间隔重采样，只保存起始时间和值
+type HistorySnapshot struct {
+	Workload string                    `json:"workload"`
+	Interval int64                     `json:"interval"` // 采样间隔（秒）
+	Series   map[string]SeriesSnapshot `json:"series"`
+}
+
+// SeriesSnapshot 一条等间隔序列
+type SeriesSnapshot struct {
+	Start  int64     `json:"start"` // 第一个点的 Unix 时间戳（秒）
+	Values []float64 `json:"values"`
+}
+
+// Snapshot 返回工作负载所有序列的快照，没有数据时返回 nil
+func (h *HistoryStore) Snapshot(workloadKey string) *HistorySnapshot {
+	snapshot := &HistorySnapshot{
+		Workload: workloadKey,
+		Interval: int64(h.interval / time.Second),
+		Series:   make(map[string]SeriesSnapshot),
+	}
+	for _, key := range h.Keys() {
+		if key != workloadKey && !strings.HasPrefix(key, workloadKey+"#") {
+			continue
+		}
+		samples := h.Samples(key)
+		if len(samples) == 0 {
+			continue
+		}
+		values := make([]float64, len(samples))
+		for i, sample := range samples {
+			values[i] = sample.Value
+		}
+		snapshot.Series[key] = SeriesSnapshot{Start: samples[0].Timestamp.Unix(), Values: values}
+	}
+	if len(snapshot.Series) == 0 {
+		return nil
+	}
+	return snapshot
+}
+
+// Restore 将快照中的样本写回存储，与已有样本按采样间隔合并
+func (h *HistoryStore) Restore(snapshot *HistorySnapshot) {
+	interval := time.Duration(snapshot.Interval) * time.Second
+	for key, series := range snapshot.Series {
+		start := time.Unix(series.Start, 0)
+		for i, value := range series.Values {
+			h.Add(key, start.Add(time.Duration(i)*interval), value)
+		}
+	}
+}
+
+// Workloads 返回存储中所有工作负载的键
+func (h *HistoryStore) Workloads() []string {
+	seen := make(map[string]bool)
+	var workloads []string
+	for _, key := range h.Keys() {
+		workload, _, _ := strings.Cut(key, "#")
+		if !seen[workload] {
+			seen[workload] = true
+			workloads = append(workloads, workload)
+		}
+	}
+	return workloads
+}
+
+// HistoryPersister 持久化工作负载历史快照，控制器重启或切换 leader 后据此恢复
+type HistoryPersister interface {
+	// Save 保存工作负载的快照，覆盖旧快照
+	Save(ctx context.Context, snapshot *HistorySnapshot) error
+	// Load 读取所有快照，无法解码的快照记录日志后跳过，不影响其他工作负载
+	Load(ctx context.Context) ([]*HistorySnapshot, error)
+	// Delete 删除工作负载的快照，不存在时不报错
+	Delete(ctx context.Context, workloadKey string) error
+}
+
+// snapshotName 根据工作负载键生成可用作对象名或文件名的标识
+func snapshotName(workloadKey string) string {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(workloadKey))
+	return fmt.Sprintf("hpamodifier-history-%x", hash.Sum64())
+}
+
+// ConfigMap 快照的标签、注解和数据键
+const (
+	historyLabel         = "autoscaling.yemo.info/history"
+	historyWorkloadAnnot = "autoscaling.yemo.info/workload"
+	historySnapshotKey   = "snapshot.json"
+)
+
+// ConfigMapPersister 在指定命名空间中为每个工作负载保存一个 ConfigMap
+type ConfigMapPersister struct {
+	Client    kubernetes.Interface
+	Namespace string
+}
+
+func (p *ConfigMapPersister) Save(ctx context.Context, snapshot *HistorySnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode history of %s: %v", snapshot.Workload, err)
+	}
+
+	configMaps := p.Client.CoreV1().ConfigMaps(p.Namespace)
+	name := snapshotName(snapshot.Workload)
+	existing, err := configMaps.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   p.Namespace,
+				Labels:      map[string]string{historyLabel: "true"},
+				Annotations: map[string]string{historyWorkloadAnnot: snapshot.Workload},
+			},
+			Data: map[string]string{historySnapshotKey: string(data)},
+		}
+		if _, err := configMaps.Create(ctx, configMap, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create history ConfigMap %s/%s: %v", p.Namespace, name, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get history ConfigMap %s/%s: %v", p.Namespace, name, err)
+	}
+
+	existing.Data = map[string]string{historySnapshotKey: string(data)}
+	if _, err := configMaps.Update(ctx, existing, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update history ConfigMap %s/%s: %v", p.Namespace, name, err)
+	}
+	return nil
+}
+
+func (p *ConfigMapPersister) Load(ctx context.Context) ([]*HistorySnapshot, error) {
+	list, err := p.Client.CoreV1().ConfigMaps(p.Namespace).List(ctx, metav1.ListOptions{LabelSelector: historyLabel + "=true"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list history ConfigMaps in %s: %v", p.Namespace, err)
+	}
+
+	var snapshots []*HistorySnapshot
+	for _, configMap := range list.Items {
+		snapshot := &HistorySnapshot{}
+		if err := json.Unmarshal([]byte(configMap.Data[historySnapshotKey]), snapshot); err != nil {
+			log.FromContext(ctx).Error(err, "skipping undecodable history snapshot",
+				"configMap", p.Namespace+"/"+configMap.Name, "workload", configMap.Annotations[historyWorkloadAnnot])
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+func (p *ConfigMapPersister) Delete(ctx context.Context, workloadKey string) error {
+	name := snapshotName(workloadKey)
+	err := p.Client.CoreV1().ConfigMaps(p.Namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete history ConfigMap %s/%s: %v", p.Namespace, name, err)
+	}
+	return nil
+}
+
+// FilePersister 在本地目录中为每个工作负载保存一个 JSON 文件，适合挂载持久卷的单副本部署
+type FilePersister struct {
+	Dir string
+}
+
+func (p *FilePersister) Save(ctx context.Context, snapshot *HistorySnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode history of %s: %v", snapshot.Workload, err)
+	}
+	if err := os.MkdirAll(p.Dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create history directory %s: %v", p.Dir, err)
+	}
+
+	// 先写临时文件再重命名，避免进程退出时留下不完整的快照
+	path := filepath.Join(p.Dir, snapshotName(snapshot.Workload)+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write history file %s: %v", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write history file %s: %v", path, err)
+	}
+	return nil
+}
+
+func (p *FilePersister) Load(ctx context.Context) ([]*HistorySnapshot, error) {
+	paths, err := filepath.Glob(filepath.Join(p.Dir, "hpamodifier-history-*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list history files in %s: %v", p.Dir, err)
+	}
+
+	var snapshots []*HistorySnapshot
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read history file %s: %v", path, err)
+		}
+		snapshot := &HistorySnapshot{}
+		if err := json.Unmarshal(data, snapshot); err != nil {
+			log.FromContext(ctx).Error(err, "skipping undecodable history snapshot", "file", path)
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+func (p *FilePersister) Delete(ctx context.Context, workloadKey string) error {
+	path := filepath.Join(p.Dir, snapshotName(workloadKey)+".json")
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete history file %s: %v", path, err)
+	}
+	return nil
+}
+
+// LoadHistory 从 HistoryPersister 恢复所有工作负载的历史数据
+func (s *ScalingManager) LoadHistory(ctx context.Context) error {
+	snapshots, err := s.HistoryPersister.Load(ctx)
+	if err != nil {
+		return err
+	}
+	store := s.strategyFactory.Store()
+	for _, snapshot := range snapshots {
+		store.Restore(snapshot)
+	}
+	return nil
+}
+
+// PruneHistory 删除不属于 workloads 中任何工作负载的历史数据和持久化的快照，
+// 用于清理控制器停止期间删除的 HPAModifier 留下的快照
+func (s *ScalingManager) PruneHistory(ctx context.Context, workloads map[string]bool) error {
+	store := s.strategyFactory.Store()
+	var failed []string
+	for _, workload := range store.Workloads() {
+		if workloads[workload] {
+			continue
+		}
+		s.mu.Lock()
+		s.forgetWorkload(workload)
+		s.mu.Unlock()
+		if err := s.HistoryPersister.Delete(ctx, workload); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to delete history of %d workloads: %s", len(failed), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// SaveHistory 保存所有工作负载的历史快照，单个工作负载失败不影响其他工作负载
+func (s *ScalingManager) SaveHistory(ctx context.Context) error {
+	store := s.strategyFactory.Store()
+	var failed []string
+	for _, workload := range store.Workloads() {
+		snapshot := store.Snapshot(workload)
+		if snapshot == nil {
+			continue
+		}
+		if err := s.HistoryPersister.Save(ctx, snapshot); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to save history of %d workloads: %s", len(failed), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// HistorySyncer 只在 leader 上运行：启动时恢复历史数据并清理已删除的 HPAModifier 的快照，之后定期保存快照。
+// 退出时不再保存，此时可能已失去 leader 身份，写入会覆盖新 leader 的快照
+type HistorySyncer struct {
+	Manager *ScalingManager
+	// Reader 读取现有的 HPAModifier，为空时不清理快照
+	Reader   client.Reader
+	Interval time.Duration
+	Log      logr.Logger
+}
+
+// NeedLeaderElection 只有 leader 写入快照，避免多个副本互相覆盖
+func (s *HistorySyncer) NeedLeaderElection() bool {
+	return true
+}
+
+// Start 实现 manager.Runnable
+func (s *HistorySyncer) Start(ctx context.Context) error {
+	ctx = log.IntoContext(ctx, s.Log)
+	if err := s.Manager.LoadHistory(ctx); err != nil {
+		// 恢复失败不影响伸缩，只是需要重新积累历史数据
+		s.Log.Error(err, "failed to load scaling history")
+	}
+	if err := s.pruneHistory(ctx); err != nil {
+		s.Log.Error(err, "failed to prune scaling history")
+	}
+
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := s.Manager.SaveHistory(ctx); err != nil {
+				s.Log.Error(err, "failed to save scaling history")
+			}
+		}
+	}
+}
+
+// pruneHistory 删除没有对应 HPAModifier 的工作负载快照
+func (s *HistorySyncer) pruneHistory(ctx context.Context) error {
+	if s.Reader == nil {
+		return nil
+	}
+	var list autoscalingv1.HPAModifierList
+	if err := s.Reader.List(ctx, &list); err != nil {
+		return fmt.Errorf("failed to list HPAModifiers: %v", err)
+	}
+	workloads := make(map[string]bool, len(list.Items))
+	for i := range list.Items {
+		workloads[workloadKey(&list.Items[i])] = true
+	}
+	return s.Manager.PruneHistory(ctx, workloads)
+}
@@ -0,0 +1,146 @@
+package scaler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+)
+
+// DefaultPodInitializationPeriod spec.podInitializationPeriod 未设置时，Pod 启动后需要检查就绪时间的时长
+const DefaultPodInitializationPeriod = 5 * time.Minute
+
+// podInitializationPeriod 返回 HPAModifier 的 Pod 初始化时间
+func podInitializationPeriod(hpa *autoscalingv1.HPAModifier) time.Duration {
+	if hpa.Spec.PodInitializationPeriod == nil {
+		return DefaultPodInitializationPeriod
+	}
+	return time.Duration(*hpa.Spec.PodInitializationPeriod) * time.Second
+}
+
+// listPods 返回匹配选择器的 Pod，按名称索引
+func (s *ScalingManager) listPods(ctx context.Context, namespace string, selector labels.Selector) (map[string]*corev1.Pod, error) {
+	list, err := s.KubeClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %v", err)
+	}
+	pods := make(map[string]*corev1.Pod, len(list.Items))
+	for i := range list.Items {
+		pods[list.Items[i].Name] = &list.Items[i]
+	}
+	return pods, nil
+}
+
+// excludePod 判断 Pod 的指标是否不应计入，需要排除时在 exclusions 中计数。sample 为 Pod 的指标样本
+func excludePod(pod *corev1.Pod, sample *metricsv1beta1.PodMetrics, now time.Time, initializationPeriod time.Duration,
+	exclusions *autoscalingv1.PodExclusions) bool {
+	switch {
+	case pod.DeletionTimestamp != nil:
+		exclusions.Terminating++
+	case pod.Status.Phase == corev1.PodFailed || pod.Status.Phase == corev1.PodSucceeded:
+		exclusions.Finished++
+	case !isPodReady(pod):
+		exclusions.Unready++
+	case isInitializing(pod, sample, now, initializationPeriod):
+		exclusions.Initializing++
+	default:
+		return false
+	}
+	return true
+}
+
+// isInitializing 与原生 HPA 相同，启动不足 initializationPeriod 的 Pod 只有在样本的统计窗口覆盖了就绪之前的时间时才算仍在初始化，
+// 即 Pod 在样本窗口内才变为就绪。没有样本或尚未就绪的 Pod 在初始化期内按初始化处理
+func isInitializing(pod *corev1.Pod, sample *metricsv1beta1.PodMetrics, now time.Time, initializationPeriod time.Duration) bool {
+	if pod.Status.StartTime == nil || now.Sub(pod.Status.StartTime.Time) >= initializationPeriod {
+		return false
+	}
+	if sample == nil || !isPodReady(pod) {
+		return true
+	}
+	readyTime := podReadyTime(pod)
+	sampleTime := sample.Timestamp.Time
+	if sampleTime.IsZero() {
+		sampleTime = now
+	}
+	return sampleTime.Add(-sample.Window.Duration).Before(readyTime)
+}
+
+// isPodReady 判断 Pod 的 Ready 条件是否为 True
+func isPodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// podReadyTime 返回 Pod 的 Ready 条件最近一次变化的时间，没有 Ready 条件时返回零值
+func podReadyTime(pod *corev1.Pod) time.Time {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.LastTransitionTime.Time
+		}
+	}
+	return time.Time{}
+}
+
+// filterPodMetrics 按样本新鲜度和 Pod 状态过滤指标：过期的样本，以及正在删除、已结束、未就绪和仍在初始化的 Pod 不计入，
+// 计入和排除的数量以及计入样本中最新的时间戳记录在 status 中。找不到 Pod 对象时无法判断状态，按原样计入。
+// 只剩仍在初始化的 Pod 时（如滚动更新刚完成）使用它们的样本，而不是停止伸缩
+func filterPodMetrics(hpa *autoscalingv1.HPAModifier, podMetrics []metricsv1beta1.PodMetrics, pods map[string]*corev1.Pod,
+	now time.Time) ([]metricsv1beta1.PodMetrics, error) {
+	initializationPeriod := podInitializationPeriod(hpa)
+	maxAge, maxWindow := metricFreshnessLimits(hpa)
+
+	var exclusions autoscalingv1.PodExclusions
+	var latest *metav1.Time
+	counted := make([]metricsv1beta1.PodMetrics, 0, len(podMetrics))
+	var initializing []metricsv1beta1.PodMetrics
+	for i, item := range podMetrics {
+		if isStaleSample(item, now, maxAge, maxWindow) {
+			exclusions.Stale++
+			continue
+		}
+		if pod, exists := pods[item.Name]; exists {
+			before := exclusions.Initializing
+			if excludePod(pod, &podMetrics[i], now, initializationPeriod, &exclusions) {
+				if exclusions.Initializing > before {
+					initializing = append(initializing, item)
+				}
+				continue
+			}
+		}
+		counted = append(counted, item)
+	}
+	if len(counted) == 0 && len(initializing) > 0 {
+		counted = initializing
+		exclusions.Initializing = 0
+	}
+	for _, item := range counted {
+		if !item.Timestamp.IsZero() && (latest == nil || item.Timestamp.After(latest.Time)) {
+			latest = item.Timestamp.DeepCopy()
+		}
+	}
+	hpa.Status.MetricPods = int32(len(counted))
+	hpa.Status.ExcludedPods = exclusions
+	if latest != nil {
+		hpa.Status.LastSampleTime = latest
+	}
+
+	if len(podMetrics) > 0 && int(exclusions.Stale) == len(podMetrics) {
+		return nil, &StaleMetricsError{Pods: len(podMetrics), MaxAge: maxAge, MaxWindow: maxWindow}
+	}
+	if len(counted) == 0 && len(podMetrics) > 0 {
+		return nil, fmt.Errorf("all %d pods with metrics are excluded (%d unready, %d initializing, %d terminating, %d finished, %d stale)",
+			len(podMetrics), exclusions.Unready, exclusions.Initializing, exclusions.Terminating, exclusions.Finished, exclusions.Stale)
+	}
+	return counted, nil
+}
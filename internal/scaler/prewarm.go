@@ -0,0 +1,96 @@
+package scaler
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+)
+
+// DefaultPodStartupLatency 尚未观测到 Pod 启动耗时时使用的默认值，预热时至少提前这么长时间扩容
+const DefaultPodStartupLatency = time.Minute
+
+// podStartupLatency 返回工作负载学习到的 Pod 启动耗时，尚未观测到 Pod 启动时返回 DefaultPodStartupLatency
+func (s *ScalingManager) podStartupLatency(hpa *autoscalingv1.HPAModifier) time.Duration {
+	if latency, ok := s.learnedStartupLatency(hpa); ok {
+		return latency
+	}
+	return DefaultPodStartupLatency
+}
+
+// forecastStep 返回预测点之间的时间间隔，内置预测器与历史数据的采样间隔一致
+func (s *ScalingManager) forecastStep(hpa *autoscalingv1.HPAModifier) time.Duration {
+	if NewForecaster(hpa.Spec.Forecaster) == nil {
+		return s.predictionStep()
+	}
+	return s.strategyFactory.SampleInterval()
+}
+
+// forecastStart 返回预测序列的起始时间，预测结果没有有效的时间戳时使用 now
+func forecastStart(prediction *PredictionResponse, now time.Time) time.Time {
+	start, err := time.Parse(time.RFC3339, prediction.Timestamp)
+	if err != nil {
+		return now
+	}
+	return start
+}
+
+// planPreWarm 在 [now, now+预热时间+Pod 启动耗时] 内找出预测的 CPU 峰值，计算峰值到来时需要的副本数。
+// 距峰值已不足 Pod 启动耗时时返回该副本数立即扩容；否则在 status.nextPreWarm 中记录计划并返回 0，
+// 之后的调谐到达计划时间时再扩容。设置 spec.metrics 时不预热
+func (s *ScalingManager) planPreWarm(ctx context.Context, hpa *autoscalingv1.HPAModifier, strategy ScalingStrategy,
+	cpuUsage float64, desiredReplicas int32, now time.Time) int32 {
+	hpa.Status.NextPreWarm = nil
+	if !strategy.ShouldPreWarm() || len(hpa.Spec.Metrics) > 0 || hpa.Spec.CPUThreshold <= 0 {
+		return 0
+	}
+
+	// 预测不可用时跳过预热
+	startup := s.podStartupLatency(hpa)
+	lead := strategy.GetPreWarmTime() + startup
+	prediction, err := s.predictOver(ctx, hpa, "cpu", cpuUsage, lead)
+	if err != nil {
+		return 0
+	}
+
+	start := forecastStart(prediction, now)
+	step := s.forecastStep(hpa)
+	deadline := now.Add(lead)
+	var peak float64
+	var peakTime time.Time
+	for i, value := range prediction.Values {
+		pointTime := start.Add(time.Duration(i+1) * step)
+		if pointTime.Before(now) {
+			continue
+		}
+		if pointTime.After(deadline) {
+			break
+		}
+		if value > peak {
+			peak, peakTime = value, pointTime
+		}
+	}
+	if peakTime.IsZero() {
+		return 0
+	}
+
+	replicas := replicasForRatio(hpa, peak/hpa.Spec.CPUThreshold)
+	if replicas > hpa.Spec.MaxReplicas {
+		replicas = hpa.Spec.MaxReplicas
+	}
+	if replicas <= desiredReplicas {
+		return 0
+	}
+	scaleUpTime := peakTime.Add(-startup)
+	if !scaleUpTime.After(now) {
+		return replicas
+	}
+	hpa.Status.NextPreWarm = &autoscalingv1.PreWarmPlan{
+		ScaleUpTime: metav1.NewTime(scaleUpTime),
+		PeakTime:    metav1.NewTime(peakTime),
+		Replicas:    replicas,
+	}
+	return 0
+}
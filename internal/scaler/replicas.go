@@ -0,0 +1,64 @@
+package scaler
+
+import (
+	"math"
+	"time"
+
+	k8sautoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+)
+
+// observeReplicas 将目标 /scale 子资源的实时副本数写入 status.currentReplicas，
+// 并记录与控制器上次写入的副本数之间的偏差
+func observeReplicas(hpa *autoscalingv1.HPAModifier, scale *k8sautoscalingv1.Scale) int32 {
+	current := scale.Spec.Replicas
+	hpa.Status.CurrentReplicas = current
+	hpa.Status.ReplicaDrift = 0
+	if hpa.Status.LastAppliedReplicas > 0 {
+		hpa.Status.ReplicaDrift = current - hpa.Status.LastAppliedReplicas
+	}
+	return current
+}
+
+// countReadyPods 统计负载已反映在指标中的 Pod，即 excludePod 不排除的 Pod，规则与 filterPodMetrics 相同，
+// 以及被排除的 Pod 中 isInitializing 认为仍在启动的 Pod
+func countReadyPods(hpa *autoscalingv1.HPAModifier, pods map[string]*corev1.Pod, podMetrics []metricsv1beta1.PodMetrics,
+	now time.Time) (int32, int32) {
+	samples := make(map[string]*metricsv1beta1.PodMetrics, len(podMetrics))
+	for i := range podMetrics {
+		samples[podMetrics[i].Name] = &podMetrics[i]
+	}
+	initializationPeriod := podInitializationPeriod(hpa)
+	var ready, starting int32
+	var exclusions autoscalingv1.PodExclusions
+	for name, pod := range pods {
+		before := exclusions
+		if !excludePod(pod, samples[name], now, initializationPeriod, &exclusions) {
+			ready++
+			continue
+		}
+		if exclusions.Initializing > before.Initializing ||
+			exclusions.Unready > before.Unready && isInitializing(pod, samples[name], now, initializationPeriod) {
+			starting++
+		}
+	}
+	return ready, starting
+}
+
+// replicasForRatio 按负载比率缩放当前副本数。就绪副本数少于当前副本数时指标只来自已就绪的 Pod，按已就绪 Pod 承担的负载计算；
+// 其中有 Pod 仍在启动时不缩容，避免在上一次扩容完成前回缩，超过初始化时间仍未就绪的 Pod 不会阻止缩容
+func replicasForRatio(hpa *autoscalingv1.HPAModifier, ratio float64) int32 {
+	current := hpa.Status.CurrentReplicas
+	ready := hpa.Status.ReadyReplicas
+	if ready <= 0 || ready >= current {
+		return int32(math.Ceil(float64(current) * ratio))
+	}
+	desired := int32(math.Ceil(float64(ready) * ratio))
+	if desired < current && hpa.Status.StartingReplicas > 0 {
+		return current
+	}
+	return desired
+}
@@ -0,0 +1,106 @@
+package scaler
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+)
+
+const (
+	// startupLatencyPercentile 预热提前量取 Pod 启动耗时的 P90，多数新 Pod 能在峰值前就绪
+	startupLatencyPercentile = 0.9
+	// maxStartupSamples 每个工作负载保留的最近 Pod 启动耗时数量
+	maxStartupSamples = 64
+)
+
+// podStartupDuration 返回 Pod 从创建到就绪的耗时。容器重启过的 Pod 的 Ready 条件时间是重新就绪的时间，不计入
+func podStartupDuration(pod *corev1.Pod) (time.Duration, bool) {
+	if pod.DeletionTimestamp != nil || pod.CreationTimestamp.IsZero() {
+		return 0, false
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.RestartCount > 0 {
+			return 0, false
+		}
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type != corev1.PodReady || condition.Status != corev1.ConditionTrue || condition.LastTransitionTime.IsZero() {
+			continue
+		}
+		duration := condition.LastTransitionTime.Sub(pod.CreationTimestamp.Time)
+		return duration, duration > 0
+	}
+	return 0, false
+}
+
+// startupSamples 一个工作负载最近 maxStartupSamples 个 Pod 的启动耗时，写满后覆盖最早的记录。
+// 只保存在内存中，控制器重启后从仍在运行的 Pod 重新学习
+type startupSamples struct {
+	recorded  map[types.UID]bool // 仍存在且已记录启动耗时的 Pod
+	durations []time.Duration
+	next      int // 写满后下一个覆盖的位置
+}
+
+// add 记录一个 Pod 的启动耗时
+func (r *startupSamples) add(duration time.Duration) {
+	if len(r.durations) < maxStartupSamples {
+		r.durations = append(r.durations, duration)
+		return
+	}
+	r.durations[r.next] = duration
+	r.next = (r.next + 1) % maxStartupSamples
+}
+
+// recordPodStartups 记录新就绪 Pod 的启动耗时，每个 Pod 只记录一次，并更新 status.podStartupLatency
+func (s *ScalingManager) recordPodStartups(hpa *autoscalingv1.HPAModifier, pods map[string]*corev1.Pod) {
+	key := workloadKey(hpa)
+
+	s.mu.Lock()
+	if s.startups == nil {
+		s.startups = make(map[string]*startupSamples)
+	}
+	samples := s.startups[key]
+	if samples == nil {
+		samples = &startupSamples{}
+		s.startups[key] = samples
+	}
+	// 只保留仍存在的 Pod，已删除的 Pod 不会再出现
+	recorded := make(map[types.UID]bool, len(pods))
+	for _, pod := range pods {
+		if samples.recorded[pod.UID] {
+			recorded[pod.UID] = true
+			continue
+		}
+		duration, ok := podStartupDuration(pod)
+		if !ok {
+			continue
+		}
+		samples.add(duration)
+		recorded[pod.UID] = true
+	}
+	samples.recorded = recorded
+	s.mu.Unlock()
+
+	if latency, ok := s.learnedStartupLatency(hpa); ok {
+		hpa.Status.PodStartupLatency = &metav1.Duration{Duration: latency}
+	}
+}
+
+// learnedStartupLatency 返回工作负载最近 Pod 启动耗时的 P90。还没有观测到 Pod 启动时返回 false
+func (s *ScalingManager) learnedStartupLatency(hpa *autoscalingv1.HPAModifier) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	samples := s.startups[workloadKey(hpa)]
+	if samples == nil || len(samples.durations) == 0 {
+		return 0, false
+	}
+	seconds := make([]float64, len(samples.durations))
+	for i, duration := range samples.durations {
+		seconds[i] = duration.Seconds()
+	}
+	return time.Duration(percentile(seconds, startupLatencyPercentile) * float64(time.Second)), true
+}
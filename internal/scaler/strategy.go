@@ -6,6 +6,8 @@ import (
 
 // ScalingStrategy 定义伸缩策略接口
 type ScalingStrategy interface {
+	// Name 获取策略名称
+	Name() string
 	// GetScalingDelay 获取伸缩延迟时间
 	GetScalingDelay() time.Duration
 	// GetScalingThreshold 获取伸缩阈值
@@ -29,6 +31,10 @@ func NewStableStrategy() *StableStrategy {
 	}
 }
 
+func (s *StableStrategy) Name() string {
+	return "Stable"
+}
+
 func (s *StableStrategy) GetScalingDelay() time.Duration {
 	return s.baseDelay
 }
@@ -58,6 +64,10 @@ func NewPeriodicStrategy() *PeriodicStrategy {
 	}
 }
 
+func (s *PeriodicStrategy) Name() string {
+	return "Periodic"
+}
+
 func (s *PeriodicStrategy) GetScalingDelay() time.Duration {
 	return s.baseDelay
 }
@@ -87,6 +97,10 @@ func NewBurstStrategy() *BurstStrategy {
 	}
 }
 
+func (s *BurstStrategy) Name() string {
+	return "Burst"
+}
+
 func (s *BurstStrategy) GetScalingDelay() time.Duration {
 	return s.baseDelay
 }
@@ -116,8 +130,21 @@ func NewStrategyFactory(historyWindow, sampleInterval time.Duration) *StrategyFa
 
 // GetStrategy 根据工作负载模式获取对应的策略
 func (f *StrategyFactory) GetStrategy(workloadKey string, currentValue float64) ScalingStrategy {
-	pattern := f.patternAnalyzer.AnalyzePattern(workloadKey, currentValue)
+	return f.StrategyFor(f.DetectPattern(workloadKey, currentValue))
+}
+
+// DetectPattern 记录当前值并识别工作负载模式
+func (f *StrategyFactory) DetectPattern(workloadKey string, currentValue float64) WorkloadPattern {
+	return f.patternAnalyzer.AnalyzePattern(workloadKey, currentValue)
+}
+
+// CurrentPattern 根据已有的历史数据识别工作负载模式，不记录新的样本
+func (f *StrategyFactory) CurrentPattern(workloadKey string) WorkloadPattern {
+	return f.patternAnalyzer.determinePattern(workloadKey)
+}
 
+// StrategyFor 返回模式对应的策略
+func (f *StrategyFactory) StrategyFor(pattern WorkloadPattern) ScalingStrategy {
 	switch pattern {
 	case PatternStable:
 		return NewStableStrategy()
@@ -129,3 +156,28 @@ func (f *StrategyFactory) GetStrategy(workloadKey string, currentValue float64)
 		return NewStableStrategy() // 默认使用稳定型策略
 	}
 }
+
+// RecordSample 记录不参与模式分析的辅助指标样本，如内存使用量
+func (f *StrategyFactory) RecordSample(key string, value float64) {
+	f.patternAnalyzer.AddSample(key, value)
+}
+
+// History 返回指定键的历史数据
+func (f *StrategyFactory) History(key string) []float64 {
+	return f.patternAnalyzer.History(key)
+}
+
+// SampleInterval 返回历史数据的采样间隔
+func (f *StrategyFactory) SampleInterval() time.Duration {
+	return f.patternAnalyzer.history.Interval()
+}
+
+// Store 返回保存历史数据的时间序列存储
+func (f *StrategyFactory) Store() *HistoryStore {
+	return f.patternAnalyzer.history
+}
+
+// Forget 删除工作负载的全部历史数据
+func (f *StrategyFactory) Forget(workloadKey string) {
+	f.patternAnalyzer.Forget(workloadKey)
+}
@@ -0,0 +1,191 @@
+package scaler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	k8sautoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+)
+
+// 伸缩目标解析失败的原因
+const (
+	// ReasonInvalidTargetRef TargetRef 的 apiVersion 无法解析
+	ReasonInvalidTargetRef = "InvalidTargetRef"
+	// ReasonUnknownKind 集群中不存在该类型
+	ReasonUnknownKind = "UnknownKind"
+	// ReasonNoScaleSubresource 该类型没有 /scale 子资源
+	ReasonNoScaleSubresource = "NoScaleSubresource"
+	// ReasonInvalidSelector 目标 Pod 的标签选择器缺失或无法解析
+	ReasonInvalidSelector = "InvalidSelector"
+	// ReasonMissingRequests 按利用率计算时目标容器没有设置 requests
+	ReasonMissingRequests = "MissingRequests"
+	// ReasonNoSelectedContainers 目标 Pod 中没有容器匹配 spec.containers
+	ReasonNoSelectedContainers = "NoSelectedContainers"
+)
+
+// 未指定 Kind/APIVersion 时默认按 Deployment 处理，兼容旧的 HPAModifier
+const (
+	defaultTargetAPIVersion = "apps/v1"
+	defaultTargetKind       = "Deployment"
+)
+
+// TargetError 表示 TargetRef 指向的工作负载无法通过 /scale 子资源伸缩
+type TargetError struct {
+	Reason  string
+	Message string
+}
+
+func (e *TargetError) Error() string {
+	return e.Message
+}
+
+// IsTargetError 判断错误是否由无法伸缩的 TargetRef 引起
+func IsTargetError(err error) bool {
+	var targetErr *TargetError
+	return errors.As(err, &targetErr)
+}
+
+// targetGroupVersionKind 返回 TargetRef 的 GVK，未指定时使用默认值
+func targetGroupVersionKind(ref corev1.ObjectReference) (schema.GroupVersionKind, error) {
+	apiVersion := ref.APIVersion
+	kind := ref.Kind
+	if kind == "" {
+		kind = defaultTargetKind
+		if apiVersion == "" {
+			apiVersion = defaultTargetAPIVersion
+		}
+	}
+
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return schema.GroupVersionKind{}, &TargetError{
+			Reason:  ReasonInvalidTargetRef,
+			Message: fmt.Sprintf("invalid targetRef apiVersion %q: %v", ref.APIVersion, err),
+		}
+	}
+	return gv.WithKind(kind), nil
+}
+
+// resolveTarget 通过 REST mapper 将 TargetRef 解析为 GroupResource，并确认其支持 /scale 子资源。
+// 解析成功的结果按 GVK 缓存，避免每次调谐都请求 discovery
+func (s *ScalingManager) resolveTarget(ref corev1.ObjectReference) (schema.GroupResource, error) {
+	gvk, err := targetGroupVersionKind(ref)
+	if err != nil {
+		return schema.GroupResource{}, err
+	}
+	s.mu.Lock()
+	gr, cached := s.scaleTargets[gvk]
+	s.mu.Unlock()
+	if cached {
+		return gr, nil
+	}
+
+	var versions []string
+	if gvk.Version != "" {
+		versions = append(versions, gvk.Version)
+	}
+	mapping, err := s.RESTMapper.RESTMapping(gvk.GroupKind(), versions...)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			return schema.GroupResource{}, &TargetError{
+				Reason:  ReasonUnknownKind,
+				Message: fmt.Sprintf("kind %s is not served by the cluster", gvk.String()),
+			}
+		}
+		return schema.GroupResource{}, fmt.Errorf("failed to map %s to a resource: %v", gvk.String(), err)
+	}
+
+	// 通过 discovery 确认该资源暴露了 /scale 子资源
+	gv := mapping.Resource.GroupVersion().String()
+	resources, err := s.KubeClient.Discovery().ServerResourcesForGroupVersion(gv)
+	if err != nil {
+		return schema.GroupResource{}, fmt.Errorf("failed to discover resources for %s: %v", gv, err)
+	}
+	scaleSubresource := mapping.Resource.Resource + "/scale"
+	for _, r := range resources.APIResources {
+		if r.Name == scaleSubresource {
+			gr = mapping.Resource.GroupResource()
+			s.mu.Lock()
+			if s.scaleTargets == nil {
+				s.scaleTargets = make(map[schema.GroupVersionKind]schema.GroupResource)
+			}
+			s.scaleTargets[gvk] = gr
+			s.mu.Unlock()
+			return gr, nil
+		}
+	}
+
+	return schema.GroupResource{}, &TargetError{
+		Reason:  ReasonNoScaleSubresource,
+		Message: fmt.Sprintf("%s %q does not expose a /scale subresource", gvk.Kind, ref.Name),
+	}
+}
+
+// getScale 获取目标工作负载的 /scale 子资源
+func (s *ScalingManager) getScale(ctx context.Context, hpa *autoscalingv1.HPAModifier) (*k8sautoscalingv1.Scale, schema.GroupResource, error) {
+	gr, err := s.resolveTarget(hpa.Spec.TargetRef)
+	if err != nil {
+		return nil, gr, err
+	}
+
+	scale, err := s.ScaleClient.Scales(hpa.Namespace).Get(ctx, gr, hpa.Spec.TargetRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, gr, fmt.Errorf("failed to get scale subresource of %s %q: %v", gr.String(), hpa.Spec.TargetRef.Name, err)
+	}
+	return scale, gr, nil
+}
+
+// resolveSelector 确定目标 Pod 的标签选择器，优先使用 spec.selector，否则读取 /scale 子资源的 status.selector
+func (s *ScalingManager) resolveSelector(ctx context.Context, hpa *autoscalingv1.HPAModifier) (labels.Selector, error) {
+	if hpa.Spec.Selector != nil {
+		return targetSelector(hpa, nil)
+	}
+	scale, _, err := s.getScale(ctx, hpa)
+	if err != nil {
+		return nil, err
+	}
+	return targetSelector(hpa, scale)
+}
+
+// targetSelector 确定目标 Pod 的标签选择器，优先使用 spec.selector，否则使用已获取的 /scale 子资源的 status.selector
+func targetSelector(hpa *autoscalingv1.HPAModifier, scale *k8sautoscalingv1.Scale) (labels.Selector, error) {
+	if hpa.Spec.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(hpa.Spec.Selector)
+		if err != nil {
+			return nil, &TargetError{
+				Reason:  ReasonInvalidSelector,
+				Message: fmt.Sprintf("invalid spec.selector: %v", err),
+			}
+		}
+		if selector.Empty() {
+			return nil, &TargetError{
+				Reason:  ReasonInvalidSelector,
+				Message: "spec.selector must not be empty",
+			}
+		}
+		return selector, nil
+	}
+
+	if scale.Status.Selector == "" {
+		return nil, &TargetError{
+			Reason:  ReasonInvalidSelector,
+			Message: fmt.Sprintf("%s %q does not report a label selector in its scale status, set spec.selector explicitly", hpa.Spec.TargetRef.Kind, hpa.Spec.TargetRef.Name),
+		}
+	}
+	selector, err := labels.Parse(scale.Status.Selector)
+	if err != nil {
+		return nil, &TargetError{
+			Reason:  ReasonInvalidSelector,
+			Message: fmt.Sprintf("invalid selector %q in scale status: %v", scale.Status.Selector, err),
+		}
+	}
+	return selector, nil
+}
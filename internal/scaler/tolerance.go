@@ -0,0 +1,41 @@
+package scaler
+
+import (
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+)
+
+// toleranceBand 返回不伸缩的负载比率区间 [lower, upper]
+func toleranceBand(hpa *autoscalingv1.HPAModifier) (float64, float64) {
+	tolerance := autoscalingv1.DefaultTolerance
+	if hpa.Spec.Tolerance != nil {
+		tolerance = *hpa.Spec.Tolerance
+	}
+	hysteresis := 0.0
+	if hpa.Spec.ScaleDownHysteresis != nil {
+		hysteresis = *hpa.Spec.ScaleDownHysteresis
+	}
+	return 1 - tolerance - hysteresis, 1 + tolerance
+}
+
+// withinTolerance 判断负载比率是否落在容差带内
+func withinTolerance(hpa *autoscalingv1.HPAModifier, ratio float64) bool {
+	lower, upper := toleranceBand(hpa)
+	return ratio >= lower && ratio <= upper
+}
+
+// applyTolerance 负载比率落在容差带内时保持当前副本数，返回调整后的副本数以及是否抑制了一次副本数变化
+func applyTolerance(hpa *autoscalingv1.HPAModifier, ratio float64, replicas int32) (int32, bool) {
+	current := hpa.Status.CurrentReplicas
+	if !withinTolerance(hpa, ratio) || replicas == current {
+		return replicas, false
+	}
+	return current, true
+}
+
+// countSuppressedDecision 容差带抑制了至少一个副本数建议、且期望副本数因此保持为当前副本数时，
+// 累计 status.suppressedDecisions。单指标和 spec.metrics 两种计算方式使用同一规则
+func countSuppressedDecision(hpa *autoscalingv1.HPAModifier, suppressed bool, desiredReplicas int32) {
+	if suppressed && desiredReplicas == hpa.Status.CurrentReplicas {
+		hpa.Status.SuppressedDecisions++
+	}
+}
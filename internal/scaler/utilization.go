@@ -0,0 +1,124 @@
+package scaler
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+)
+
+// resourceTotals 一种资源在所有计入的容器上的用量和 requests 之和
+type resourceTotals struct {
+	usage   int64 // CPU 为毫核，内存为字节
+	request int64
+	missing []string // 缺少 requests 的容器，格式为 <pod>/<container>
+}
+
+// add 累加一个容器的用量，request 为 0 的容器记为缺少 requests，不计入总和
+func (t *resourceTotals) add(pod, container string, usage, request int64) {
+	if request <= 0 {
+		t.missing = append(t.missing, pod+"/"+container)
+		return
+	}
+	t.usage += usage
+	t.request += request
+}
+
+// merge 累加另一组容器的用量和 requests
+func (t *resourceTotals) merge(other resourceTotals) {
+	t.usage += other.usage
+	t.request += other.request
+	t.missing = append(t.missing, other.missing...)
+}
+
+// utilization 返回用量占 requests 的比例
+func (t *resourceTotals) utilization(name corev1.ResourceName, hpa *autoscalingv1.HPAModifier) (float64, error) {
+	if len(t.missing) > 0 && hpa.Spec.MissingRequests != autoscalingv1.MissingRequestsIgnoreContainer {
+		return 0, &TargetError{
+			Reason: ReasonMissingRequests,
+			Message: fmt.Sprintf("cannot compute %s utilization, containers without a %s request: %s (set missingRequests to IgnoreContainer to skip them)",
+				name, name, strings.Join(t.missing, ", ")),
+		}
+	}
+	if t.request == 0 {
+		return 0, &TargetError{
+			Reason:  ReasonMissingRequests,
+			Message: fmt.Sprintf("cannot compute %s utilization, no container of the target pods has a %s request", name, name),
+		}
+	}
+	return float64(t.usage) / float64(t.request), nil
+}
+
+// resourceUtilization 与原生 HPA 相同，用所有 Pod 的用量之和除以 requests 之和计算利用率；
+// aggregation 不为 Mean 时先计算每个 Pod 的利用率再跨 Pod 汇总。
+// requests 从 Pod spec 中读取，已不存在的 Pod 和不匹配 spec.containers 的容器不计入
+func resourceUtilization(hpa *autoscalingv1.HPAModifier, selector labels.Selector, podMetrics []metricsv1beta1.PodMetrics,
+	pods map[string]*corev1.Pod) (float64, float64, error) {
+	requests := make(map[string]map[string]corev1.ResourceList, len(pods))
+	for name, pod := range pods {
+		containers := make(map[string]corev1.ResourceList, len(pod.Spec.Containers))
+		for _, container := range pod.Spec.Containers {
+			containers[container.Name] = container.Resources.Requests
+		}
+		requests[name] = containers
+	}
+
+	var cpu, memory resourceTotals
+	var cpuValues, memoryValues []float64
+	podCount, selectedPods := 0, 0
+	for _, pod := range podMetrics {
+		containers, exists := requests[pod.Name]
+		if !exists {
+			continue
+		}
+		podCount++
+		var podCPU, podMemory resourceTotals
+		selected := false
+		for _, container := range pod.Containers {
+			if !includeContainer(hpa, container.Name) {
+				continue
+			}
+			request := containers[container.Name]
+			podCPU.add(pod.Name, container.Name, container.Usage.Cpu().MilliValue(), request.Cpu().MilliValue())
+			podMemory.add(pod.Name, container.Name, container.Usage.Memory().Value(), request.Memory().Value())
+			selected = true
+		}
+		if !selected {
+			continue
+		}
+		selectedPods++
+		cpu.merge(podCPU)
+		memory.merge(podMemory)
+		if podCPU.request > 0 {
+			cpuValues = append(cpuValues, float64(podCPU.usage)/float64(podCPU.request))
+		}
+		if podMemory.request > 0 {
+			memoryValues = append(memoryValues, float64(podMemory.usage)/float64(podMemory.request))
+		}
+	}
+	if podCount == 0 {
+		return 0, 0, fmt.Errorf("no running pods found for %s %s matching selector %q",
+			hpa.Spec.TargetRef.Kind, hpa.Spec.TargetRef.Name, selector.String())
+	}
+	if selectedPods == 0 {
+		return 0, 0, noSelectedContainersError(podCount)
+	}
+
+	cpuUtilization, err := cpu.utilization(corev1.ResourceCPU, hpa)
+	if err != nil {
+		return 0, 0, err
+	}
+	memoryUtilization, err := memory.utilization(corev1.ResourceMemory, hpa)
+	if err != nil {
+		return 0, 0, err
+	}
+	if fn := aggregationFunction(hpa); fn != autoscalingv1.AggregationMean {
+		cpuUtilization = aggregate(fn, cpuValues)
+		memoryUtilization = aggregate(fn, memoryValues)
+	}
+	return cpuUtilization, memoryUtilization, nil
+}
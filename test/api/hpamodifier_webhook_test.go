@@ -0,0 +1,173 @@
+package api_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+)
+
+func newHPAModifier(name, target string) *autoscalingv1.HPAModifier {
+	return &autoscalingv1.HPAModifier{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: autoscalingv1.HPAModifierSpec{
+			TargetRef:   corev1.ObjectReference{Name: target},
+			MaxReplicas: 10,
+		},
+	}
+}
+
+func newValidator(objects ...runtime.Object) *autoscalingv1.HPAModifierValidator {
+	scheme := runtime.NewScheme()
+	_ = autoscalingv1.AddToScheme(scheme)
+	return &autoscalingv1.HPAModifierValidator{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objects...).Build(),
+	}
+}
+
+func TestHPAModifierDefault(t *testing.T) {
+	hpa := newHPAModifier("web", "web")
+	assert.NoError(t, (&autoscalingv1.HPAModifierDefaulter{}).Default(context.Background(), hpa))
+
+	assert.Equal(t, autoscalingv1.DefaultMinReplicas, hpa.Spec.MinReplicas)
+	assert.Equal(t, autoscalingv1.DefaultCPUThreshold, hpa.Spec.CPUThreshold)
+	assert.Equal(t, autoscalingv1.DefaultMemoryThreshold, hpa.Spec.MemoryThreshold)
+	assert.Equal(t, autoscalingv1.DefaultPredictionWindow, hpa.Spec.PredictionWindow)
+	assert.Equal(t, autoscalingv1.ForecasterExternal, hpa.Spec.Forecaster)
+	assert.Equal(t, autoscalingv1.MetricsSourceMetricsServer, hpa.Spec.MetricsSource)
+	assert.Equal(t, autoscalingv1.ThresholdModeAbsolute, hpa.Spec.ThresholdMode)
+	assert.Equal(t, autoscalingv1.MissingRequestsFail, hpa.Spec.MissingRequests)
+	assert.Equal(t, autoscalingv1.AggregationMean, hpa.Spec.Aggregation)
+	assert.Equal(t, autoscalingv1.DefaultTolerance, *hpa.Spec.Tolerance)
+
+	// 已设置的值保持不变
+	hpa.Spec.CPUThreshold = 0.5
+	assert.NoError(t, (&autoscalingv1.HPAModifierDefaulter{}).Default(context.Background(), hpa))
+	assert.Equal(t, 0.5, hpa.Spec.CPUThreshold)
+}
+
+func TestHPAModifierValidateSpec(t *testing.T) {
+	validator := newValidator()
+
+	valid := newHPAModifier("web", "web")
+	assert.NoError(t, (&autoscalingv1.HPAModifierDefaulter{}).Default(context.Background(), valid))
+	_, err := validator.ValidateCreate(context.Background(), valid)
+	assert.NoError(t, err)
+
+	invalid := valid.DeepCopy()
+	invalid.Spec.MinReplicas = 5
+	invalid.Spec.MaxReplicas = 2
+	invalid.Spec.CPUThreshold = 0
+	invalid.Spec.PredictionWindow = -1
+	invalid.Spec.TargetRef.Namespace = "other"
+	invalid.Spec.Behavior = &autoscalingv2.HorizontalPodAutoscalerBehavior{
+		ScaleUp: &autoscalingv2.HPAScalingRules{
+			Policies: []autoscalingv2.HPAScalingPolicy{{Type: autoscalingv2.PodsScalingPolicy, Value: 0, PeriodSeconds: 60}},
+		},
+	}
+	invalid.Spec.Metrics = []autoscalingv2.MetricSpec{
+		{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name:   corev1.ResourceCPU,
+				Target: autoscalingv2.MetricTarget{Type: autoscalingv2.ValueMetricType},
+			},
+		},
+		{
+			Type: autoscalingv2.ExternalMetricSourceType,
+			External: &autoscalingv2.ExternalMetricSource{
+				Metric: autoscalingv2.MetricIdentifier{Name: "queue_depth"},
+				Target: autoscalingv2.MetricTarget{Type: autoscalingv2.ValueMetricType},
+			},
+		},
+		{Type: autoscalingv2.PodsMetricSourceType},
+	}
+	invalid.Spec.PrometheusQueries = &autoscalingv1.PrometheusQueries{CPU: "rate(cpu{namespace=\"{{.Namespace\"}[2m])"}
+	invalid.Spec.MetricFreshness = &autoscalingv1.MetricFreshness{MaxAgeSeconds: -1}
+	hysteresis := 0.95
+	invalid.Spec.ScaleDownHysteresis = &hysteresis
+	invalid.Spec.Containers = &autoscalingv1.ContainerSelector{Include: []string{"app"}, Exclude: []string{"app", ""}}
+	_, err = validator.ValidateCreate(context.Background(), invalid)
+	assert.True(t, apierrors.IsInvalid(err))
+	for _, field := range []string{
+		"spec.maxReplicas",
+		"spec.cpuThreshold",
+		"spec.predictionWindow",
+		"spec.targetRef.namespace",
+		"spec.behavior.scaleUp.policies[0].value",
+		"spec.metrics[0].resource.target.type",
+		"spec.metrics[1].external.target",
+		"spec.metrics[2].pods",
+		"spec.prometheusQueries: Forbidden",
+		"spec.prometheusQueries.cpu",
+		"spec.metricFreshness.maxAgeSeconds",
+		"spec.scaleDownHysteresis",
+		"spec.containers.exclude[0]",
+		"spec.containers.exclude[1]",
+	} {
+		assert.Contains(t, err.Error(), field)
+	}
+}
+
+func TestHPAModifierValidateDuplicateTarget(t *testing.T) {
+	existing := newHPAModifier("web", "web")
+	existing.Spec.TargetRef.APIVersion = "apps/v1"
+	existing.Spec.TargetRef.Kind = "Deployment"
+	validator := newValidator(existing)
+
+	// 未指定 Kind 时按 Deployment 处理，与已有对象冲突
+	duplicate := newHPAModifier("web-2", "web")
+	assert.NoError(t, (&autoscalingv1.HPAModifierDefaulter{}).Default(context.Background(), duplicate))
+	_, err := validator.ValidateCreate(context.Background(), duplicate)
+	assert.True(t, apierrors.IsInvalid(err))
+	assert.Contains(t, err.Error(), `already scaled by HPAModifier "web"`)
+
+	// 同名的 StatefulSet 是另一个工作负载
+	duplicate.Spec.TargetRef.APIVersion = "apps/v1"
+	duplicate.Spec.TargetRef.Kind = "StatefulSet"
+	_, err = validator.ValidateCreate(context.Background(), duplicate)
+	assert.NoError(t, err)
+
+	// 更新自身不算冲突
+	update := existing.DeepCopy()
+	assert.NoError(t, (&autoscalingv1.HPAModifierDefaulter{}).Default(context.Background(), update))
+	_, err = validator.ValidateUpdate(context.Background(), existing, update)
+	assert.NoError(t, err)
+}
+
+func TestHPAModifierValidateUpdateDuplicateTarget(t *testing.T) {
+	// 启用 webhook 前已存在两个指向同一工作负载的对象
+	first := newHPAModifier("web", "web")
+	second := newHPAModifier("web-2", "web")
+	api := newHPAModifier("api", "api")
+	for _, hpa := range []*autoscalingv1.HPAModifier{first, second, api} {
+		assert.NoError(t, (&autoscalingv1.HPAModifierDefaulter{}).Default(context.Background(), hpa))
+	}
+	validator := newValidator(first, second, api)
+
+	// targetRef 未改变时仍可修改其他字段
+	update := second.DeepCopy()
+	update.Spec.MaxReplicas = 20
+	_, err := validator.ValidateUpdate(context.Background(), second, update)
+	assert.NoError(t, err)
+
+	// targetRef 改为已被其他对象伸缩的工作负载时拒绝
+	update = api.DeepCopy()
+	update.Spec.TargetRef.Name = "web"
+	_, err = validator.ValidateUpdate(context.Background(), api, update)
+	assert.True(t, apierrors.IsInvalid(err))
+	assert.Contains(t, err.Error(), "already scaled by HPAModifier")
+
+	// 改为没有冲突的工作负载
+	update.Spec.TargetRef.Name = "worker"
+	_, err = validator.ValidateUpdate(context.Background(), api, update)
+	assert.NoError(t, err)
+}
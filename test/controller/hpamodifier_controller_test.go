@@ -0,0 +1,107 @@
+package controller_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	fakescale "k8s.io/client-go/scale/fake"
+	k8stesting "k8s.io/client-go/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+	"yemo.info/auto-scaling-system/internal/controller"
+	"yemo.info/auto-scaling-system/internal/scaler"
+)
+
+func TestReconcileRestoresNativeHPABeforeDeletion(t *testing.T) {
+	ctx := context.Background()
+	minReplicas := int32(3)
+	nativeHPA := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"},
+			MinReplicas:    &minReplicas,
+			MaxReplicas:    5,
+		},
+	}
+	kubeClient := kubefake.NewSimpleClientset(nativeHPA)
+	kubeClient.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "apps/v1",
+		APIResources: []metav1.APIResource{{Name: "deployments"}, {Name: "deployments/scale"}},
+	}}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
+
+	hpaModifier := &autoscalingv1.HPAModifier{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: autoscalingv1.HPAModifierSpec{
+			TargetRef:   corev1.ObjectReference{Kind: "Deployment", Name: "web"},
+			MinReplicas: 1,
+			MaxReplicas: 10,
+			NativeHPA:   autoscalingv1.NativeHPAModify,
+		},
+	}
+	scheme := runtime.NewScheme()
+	_ = autoscalingv1.AddToScheme(scheme)
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(hpaModifier).
+		WithStatusSubresource(hpaModifier).Build()
+
+	scaleClient := &fakescale.FakeScaleClient{}
+	scaleClient.AddReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewNotFound(schema.GroupResource{Group: "apps", Resource: "deployments"}, "web")
+	})
+	reconciler := &controller.HPAModifierReconciler{
+		Client:     k8sClient,
+		Scheme:     scheme,
+		Log:        logr.Discard(),
+		ScalingMgr: scaler.NewScalingManager(kubeClient, scaleClient, mapper, nil, ""),
+		KubeClient: kubeClient,
+	}
+	request := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(hpaModifier)}
+
+	// Modify 模式在修改原生 HPA 之前加上 finalizer
+	_, err := reconciler.Reconcile(ctx, request)
+	assert.NoError(t, err)
+	current := &autoscalingv1.HPAModifier{}
+	assert.NoError(t, k8sClient.Get(ctx, request.NamespacedName, current))
+	assert.Contains(t, current.Finalizers, controller.NativeHPAFinalizer)
+
+	// 模拟控制器停止期间修改过原生 HPA 后被删除
+	hpas := kubeClient.AutoscalingV2().HorizontalPodAutoscalers("default")
+	modified, err := hpas.Get(ctx, "web", metav1.GetOptions{})
+	assert.NoError(t, err)
+	minReplicas = 4
+	modified.Annotations = map[string]string{
+		"autoscaling.yemo.info/modified-by":           "web",
+		"autoscaling.yemo.info/original-min-replicas": "3",
+	}
+	modified.Spec.MinReplicas = &minReplicas
+	_, err = hpas.Update(ctx, modified, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	assert.NoError(t, k8sClient.Delete(ctx, current))
+
+	// finalizer 保留到原生 HPA 恢复之后
+	assert.NoError(t, k8sClient.Get(ctx, request.NamespacedName, current))
+	assert.False(t, current.DeletionTimestamp.IsZero())
+	_, err = reconciler.Reconcile(ctx, request)
+	assert.NoError(t, err)
+	restored, err := hpas.Get(ctx, "web", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), *restored.Spec.MinReplicas)
+	assert.Empty(t, restored.Annotations)
+	err = k8sClient.Get(ctx, request.NamespacedName, current)
+	assert.True(t, apierrors.IsNotFound(err))
+}
@@ -0,0 +1,117 @@
+package metrics_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	fakemetrics "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+
+	"yemo.info/auto-scaling-system/internal/metrics"
+)
+
+// newCountingMetricsClientset 返回 web 和 api 各一个 Pod 的指标，并统计 List 请求的次数和选择器
+func newCountingMetricsClientset(lists *int, selectors *[]string) *fakemetrics.Clientset {
+	var mu sync.Mutex
+	clientset := fakemetrics.NewSimpleClientset()
+	clientset.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		*lists++
+		*selectors = append(*selectors, action.(k8stesting.ListAction).GetListRestrictions().Labels.String())
+		list := &metricsv1beta1.PodMetricsList{}
+		for _, app := range []string{"web", "api"} {
+			list.Items = append(list.Items, metricsv1beta1.PodMetrics{ObjectMeta: metav1.ObjectMeta{
+				Name: app + "-0", Namespace: action.GetNamespace(), Labels: map[string]string{"app": app},
+			}})
+		}
+		return true, list, nil
+	})
+	return clientset
+}
+
+func TestCachedMetricsClientSharesNamespaceList(t *testing.T) {
+	var lists int
+	var selectors []string
+	client := metrics.NewCachedMetricsClient(
+		metrics.NewK8sMetricsClient(newCountingMetricsClientset(&lists, &selectors)), time.Minute)
+
+	// 同一命名空间的并发调用只发起一次 List，按选择器在本地过滤
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			app := []string{"web", "api"}[i%2]
+			podMetrics, err := client.GetPodMetrics(context.Background(), "shop", labels.SelectorFromSet(labels.Set{"app": app}))
+			assert.NoError(t, err)
+			if assert.Len(t, podMetrics.Items, 1) {
+				assert.Equal(t, app+"-0", podMetrics.Items[0].Name)
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 1, lists)
+	assert.Equal(t, []string{""}, selectors)
+
+	// 其他命名空间单独缓存
+	_, err := client.GetPodMetrics(context.Background(), "default", labels.Everything())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, lists)
+}
+
+func TestCachedMetricsClientExpiry(t *testing.T) {
+	var lists int
+	var selectors []string
+	client := metrics.NewCachedMetricsClient(
+		metrics.NewK8sMetricsClient(newCountingMetricsClientset(&lists, &selectors)), 20*time.Millisecond)
+
+	selector := labels.SelectorFromSet(labels.Set{"app": "web"})
+	for i := 0; i < 2; i++ {
+		_, err := client.GetPodMetrics(context.Background(), "shop", selector)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, lists)
+
+	time.Sleep(30 * time.Millisecond)
+	_, err := client.GetPodMetrics(context.Background(), "shop", selector)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, lists)
+
+	// ttl 为 0 时不缓存，选择器在服务端过滤
+	lists, selectors = 0, nil
+	uncached := metrics.NewCachedMetricsClient(
+		metrics.NewK8sMetricsClient(newCountingMetricsClientset(&lists, &selectors)), 0)
+	for i := 0; i < 2; i++ {
+		_, err := uncached.GetPodMetrics(context.Background(), "shop", selector)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 2, lists)
+	assert.Equal(t, []string{"app=web", "app=web"}, selectors)
+}
+
+func TestCachedMetricsClientDoesNotCacheErrors(t *testing.T) {
+	clientset := fakemetrics.NewSimpleClientset()
+	failures := 1
+	clientset.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if failures > 0 {
+			failures--
+			return true, nil, fmt.Errorf("metrics-server unavailable")
+		}
+		return true, &metricsv1beta1.PodMetricsList{}, nil
+	})
+	client := metrics.NewCachedMetricsClient(metrics.NewK8sMetricsClient(clientset), time.Minute)
+
+	_, err := client.GetPodMetrics(context.Background(), "shop", labels.Everything())
+	assert.Error(t, err)
+	_, err = client.GetPodMetrics(context.Background(), "shop", labels.Everything())
+	assert.NoError(t, err)
+}
@@ -0,0 +1,144 @@
+package metrics_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+	"yemo.info/auto-scaling-system/internal/metrics"
+	"yemo.info/auto-scaling-system/internal/prometheus"
+	"yemo.info/auto-scaling-system/internal/prometheus/prometheustest"
+)
+
+func newTestHPAModifier() *autoscalingv1.HPAModifier {
+	return &autoscalingv1.HPAModifier{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+		Spec: autoscalingv1.HPAModifierSpec{
+			TargetRef:     corev1.ObjectReference{Kind: "Deployment", Name: "web.v2"},
+			MetricsSource: autoscalingv1.MetricsSourcePrometheus,
+		},
+	}
+}
+
+// podSeries 返回一个 Pod 中一个容器的即时查询结果
+func podSeries(pod, container string, ts time.Time, value float64) prometheus.Series {
+	return prometheus.Series{
+		Labels: map[string]string{"pod": pod, "container": container},
+		Points: []prometheus.Point{{Timestamp: ts, Value: value}},
+	}
+}
+
+func TestPrometheusMetricsClientGetPodMetrics(t *testing.T) {
+	now := time.Unix(1735689600, 0)
+	server := prometheustest.NewServer(func(query prometheustest.Query) ([]prometheus.Series, error) {
+		if strings.Contains(query.Query, "container_memory_working_set_bytes") {
+			return []prometheus.Series{
+				podSeries("web.v2-a", "app", now, 512*1024*1024),
+				podSeries("web.v2-a", "sidecar", now, 64*1024*1024),
+				podSeries("web.v2-b", "app", now, 256*1024*1024),
+			}, nil
+		}
+		return []prometheus.Series{
+			podSeries("web.v2-a", "app", now, 0.25),
+			podSeries("web.v2-a", "sidecar", now, 0.05),
+			podSeries("web.v2-b", "app", now, 0.5),
+		}, nil
+	})
+	defer server.Close()
+
+	source, err := metrics.NewPrometheusMetricsClient(prometheus.NewClient(server.URL, time.Second),
+		metrics.DefaultCPUQuery, metrics.DefaultMemoryQuery)
+	assert.NoError(t, err)
+	client, err := source.ForWorkload(newTestHPAModifier())
+	assert.NoError(t, err)
+
+	podMetrics, err := client.GetPodMetrics(context.Background(), "shop", labels.SelectorFromSet(labels.Set{"app": "web"}))
+	assert.NoError(t, err)
+	assert.Len(t, podMetrics.Items, 2)
+
+	pod := podMetrics.Items[0]
+	assert.Equal(t, "web.v2-a", pod.Name)
+	assert.True(t, now.Equal(pod.Timestamp.Time))
+	assert.Len(t, pod.Containers, 2)
+	assert.Equal(t, "app", pod.Containers[0].Name)
+	assert.Equal(t, int64(250), pod.Containers[0].Usage.Cpu().MilliValue())
+	assert.Equal(t, int64(512*1024*1024), pod.Containers[0].Usage.Memory().Value())
+	assert.Equal(t, int64(500), podMetrics.Items[1].Containers[0].Usage.Cpu().MilliValue())
+
+	queries := server.Queries()
+	assert.Len(t, queries, 2)
+	for _, query := range queries {
+		assert.Equal(t, "/api/v1/query", query.Path)
+		assert.Contains(t, query.Query, `namespace="shop"`)
+		// 按标签选择器关联 kube_pod_labels，不按 Pod 名称前缀匹配
+		assert.Contains(t, query.Query, `kube_pod_labels{namespace="shop",label_app="web"}`)
+		assert.NotContains(t, query.Query, `pod=~`)
+	}
+}
+
+func TestPrometheusMetricsClientQueryOverrides(t *testing.T) {
+	server := prometheustest.NewServer(func(query prometheustest.Query) ([]prometheus.Series, error) {
+		return nil, nil
+	})
+	defer server.Close()
+
+	source, err := metrics.NewPrometheusMetricsClient(prometheus.NewClient(server.URL, time.Second),
+		metrics.DefaultCPUQuery, metrics.DefaultMemoryQuery)
+	assert.NoError(t, err)
+
+	hpa := newTestHPAModifier()
+	hpa.Spec.PrometheusQueries = &autoscalingv1.PrometheusQueries{
+		CPU: `sum by (pod) (rate(http_requests_total{namespace="{{.Namespace}}",workload="{{.Workload}}"}[1m])` +
+			` * on (pod) group_left() kube_pod_labels{ {{.LabelMatchers}} })`,
+	}
+	client, err := source.ForWorkload(hpa)
+	assert.NoError(t, err)
+
+	selector, err := labels.Parse("app=web,tier in (backend,api),canary!=true")
+	assert.NoError(t, err)
+	// 两个查询都没有结果时返回错误，提示检查 kube-state-metrics 是否导出了 Pod 标签
+	_, err = client.GetPodMetrics(context.Background(), "shop", selector)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "--metric-labels-allowlist")
+
+	queries := server.Queries()
+	assert.Len(t, queries, 2)
+	// CPU 使用覆盖后的查询，内存仍使用默认查询
+	assert.Contains(t, queries[0].Query, `http_requests_total{namespace="shop",workload="web.v2"}`)
+	assert.Contains(t, queries[0].Query, `label_app="web"`)
+	assert.Contains(t, queries[0].Query, `label_canary!="true"`)
+	assert.Contains(t, queries[0].Query, `label_tier=~"api|backend"`)
+	assert.Contains(t, queries[1].Query, "container_memory_working_set_bytes")
+
+	// 无效的模板
+	hpa.Spec.PrometheusQueries.Memory = "{{.Namespace"
+	_, err = source.ForWorkload(hpa)
+	assert.Error(t, err)
+	_, err = metrics.NewPrometheusMetricsClient(prometheus.NewClient(server.URL, time.Second), "{{.Missing}", metrics.DefaultMemoryQuery)
+	assert.Error(t, err)
+}
+
+func TestPrometheusMetricsClientErrors(t *testing.T) {
+	server := prometheustest.NewServer(func(query prometheustest.Query) ([]prometheus.Series, error) {
+		return nil, fmt.Errorf("unknown function rat")
+	})
+	defer server.Close()
+
+	source, err := metrics.NewPrometheusMetricsClient(prometheus.NewClient(server.URL, time.Second),
+		metrics.DefaultCPUQuery, metrics.DefaultMemoryQuery)
+	assert.NoError(t, err)
+	client, err := source.ForWorkload(newTestHPAModifier())
+	assert.NoError(t, err)
+
+	_, err = client.GetPodMetrics(context.Background(), "shop", labels.Everything())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown function rat")
+}
@@ -0,0 +1,168 @@
+package predictor_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"yemo.info/auto-scaling-system/internal/predictor"
+)
+
+// newTestClient 创建重试间隔很短的客户端，避免测试耗时
+func newTestClient(maxRetries, failureThreshold int) *predictor.Client {
+	return predictor.NewClient(predictor.Options{
+		Timeout:          200 * time.Millisecond,
+		MaxRetries:       maxRetries,
+		RetryBackoff:     time.Millisecond,
+		FailureThreshold: failureThreshold,
+		OpenDuration:     50 * time.Millisecond,
+	})
+}
+
+var testRequest = predictor.Request{
+	Target:    "cpu",
+	Namespace: "default",
+	Workload:  "nginx-deployment",
+	Horizon:   5 * time.Minute,
+	Step:      time.Minute,
+}
+
+func TestPredictRetriesServerErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(predictor.PredictionResponse{Values: []float64{0.5}})
+	}))
+	defer server.Close()
+
+	result, err := newTestClient(2, 5).Predict(context.Background(), server.URL, testRequest)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{0.5}, result.Values)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestPredictTypedErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		switch r.URL.Query().Get("target") {
+		case "html":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte("<html><body>Internal Server Error</body></html>"))
+		case "bad-request":
+			w.WriteHeader(http.StatusBadRequest)
+		case "garbage":
+			_, _ = w.Write([]byte("not json"))
+		case "slow":
+			time.Sleep(500 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+	client := newTestClient(1, 100)
+
+	request := testRequest
+	request.Target = "html"
+	_, err := client.Predict(context.Background(), server.URL, request)
+	assert.Equal(t, predictor.ReasonBadStatus, predictor.ReasonOf(err))
+	assert.Contains(t, err.Error(), "returned 500")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls), "5xx 应重试")
+
+	// 4xx 不重试
+	atomic.StoreInt32(&calls, 0)
+	request.Target = "bad-request"
+	_, err = client.Predict(context.Background(), server.URL, request)
+	assert.Equal(t, predictor.ReasonBadStatus, predictor.ReasonOf(err))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	request.Target = "garbage"
+	_, err = client.Predict(context.Background(), server.URL, request)
+	assert.Equal(t, predictor.ReasonInvalidResponse, predictor.ReasonOf(err))
+
+	request.Target = "slow"
+	_, err = client.Predict(context.Background(), server.URL, request)
+	assert.Equal(t, predictor.ReasonTimeout, predictor.ReasonOf(err))
+}
+
+func TestPredictCircuitBreaker(t *testing.T) {
+	var healthy atomic.Bool
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(predictor.PredictionResponse{Values: []float64{0.5}})
+	}))
+	defer server.Close()
+	client := newTestClient(0, 2)
+
+	// 连续失败两次后熔断，第三次请求不会发出
+	for i := 0; i < 2; i++ {
+		_, err := client.Predict(context.Background(), server.URL, testRequest)
+		assert.Equal(t, predictor.ReasonBadStatus, predictor.ReasonOf(err))
+	}
+	_, err := client.Predict(context.Background(), server.URL, testRequest)
+	assert.Equal(t, predictor.ReasonCircuitOpen, predictor.ReasonOf(err))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	// 其他地址的熔断器不受影响
+	_, err = client.Predict(context.Background(), "http://127.0.0.1:1", testRequest)
+	assert.Equal(t, predictor.ReasonUnreachable, predictor.ReasonOf(err))
+
+	// 打开时间过后放行试探请求，成功则关闭熔断器
+	healthy.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	_, err = client.Predict(context.Background(), server.URL, testRequest)
+	assert.NoError(t, err)
+	_, err = client.Predict(context.Background(), server.URL, testRequest)
+	assert.NoError(t, err)
+}
+
+func TestPredictBackoffCapped(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	// 重试次数超过 64 次时退避时间的移位会溢出，应按上限等待而不是 panic
+	client := predictor.NewClient(predictor.Options{
+		Timeout:          200 * time.Millisecond,
+		MaxRetries:       70,
+		RetryBackoff:     time.Millisecond,
+		MaxRetryBackoff:  2 * time.Millisecond,
+		FailureThreshold: 100,
+	})
+	_, err := client.Predict(context.Background(), server.URL, testRequest)
+	assert.Equal(t, predictor.ReasonBadStatus, predictor.ReasonOf(err))
+	assert.Equal(t, int32(71), atomic.LoadInt32(&calls))
+}
+
+func TestPredictCanceledContextDoesNotTripBreaker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(predictor.PredictionResponse{Values: []float64{0.5}})
+	}))
+	defer server.Close()
+	client := newTestClient(2, 1)
+
+	// 调谐被取消时返回 Canceled，熔断器仍然关闭
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := client.Predict(ctx, server.URL, testRequest)
+	assert.Equal(t, predictor.ReasonCanceled, predictor.ReasonOf(err))
+
+	result, err := client.Predict(context.Background(), server.URL, testRequest)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{0.5}, result.Values)
+}
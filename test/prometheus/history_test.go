@@ -0,0 +1,90 @@
+package prometheus_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+	"yemo.info/auto-scaling-system/internal/prometheus"
+	"yemo.info/auto-scaling-system/internal/prometheus/prometheustest"
+)
+
+func newTestHPAModifier() *autoscalingv1.HPAModifier {
+	return &autoscalingv1.HPAModifier{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+		Spec: autoscalingv1.HPAModifierSpec{
+			TargetRef: corev1.ObjectReference{Kind: "Deployment", Name: "web.v2"},
+		},
+	}
+}
+
+// testSelector 目标 Pod 的标签选择器
+var testSelector = labels.SelectorFromSet(labels.Set{"app.kubernetes.io/name": "web"})
+
+func TestHistorySourceQueries(t *testing.T) {
+	start := time.Unix(1735689600, 0)
+	server := prometheustest.NewServer(func(query prometheustest.Query) ([]prometheus.Series, error) {
+		value := 0.5
+		if strings.Contains(query.Query, "container_memory_working_set_bytes") {
+			value = 1.5
+		}
+		var points []prometheus.Point
+		for ts := query.Start; !ts.After(query.End); ts = ts.Add(query.Step) {
+			points = append(points, prometheus.Point{Timestamp: ts, Value: value})
+		}
+		return []prometheus.Series{{Labels: map[string]string{}, Points: points}}, nil
+	})
+	defer server.Close()
+
+	source := &prometheus.HistorySource{Client: prometheus.NewClient(server.URL, time.Second)}
+	cpu, memory, err := source.WorkloadHistory(context.Background(), newTestHPAModifier(), testSelector, start, start.Add(time.Hour), 5*time.Minute)
+	assert.NoError(t, err)
+	assert.Len(t, cpu, 13)
+	assert.Len(t, memory, 13)
+	assert.Equal(t, 0.5, cpu[0].Value)
+	assert.Equal(t, 1.5, memory[12].Value)
+	assert.True(t, start.Equal(cpu[0].Timestamp))
+
+	queries := server.Queries()
+	assert.Len(t, queries, 2)
+	for _, query := range queries {
+		assert.Equal(t, "/api/v1/query_range", query.Path)
+		assert.Equal(t, 5*time.Minute, query.Step)
+		assert.True(t, start.Equal(query.Start))
+		// 按命名空间和标签选择器关联 kube_pod_labels，不再按 Pod 名称前缀匹配
+		assert.Contains(t, query.Query, `namespace="shop"`)
+		assert.Contains(t, query.Query, `kube_pod_labels{namespace="shop",label_app_kubernetes_io_name="web"}`)
+		assert.NotContains(t, query.Query, `pod=~`)
+	}
+}
+
+func TestHistorySourceErrors(t *testing.T) {
+	server := prometheustest.NewServer(func(query prometheustest.Query) ([]prometheus.Series, error) {
+		return nil, fmt.Errorf("parse error at char 5")
+	})
+	defer server.Close()
+
+	source := &prometheus.HistorySource{Client: prometheus.NewClient(server.URL, time.Second)}
+	_, _, err := source.WorkloadHistory(context.Background(), newTestHPAModifier(), testSelector, time.Now().Add(-time.Hour), time.Now(), time.Minute)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "parse error at char 5")
+
+	// 没有数据时返回空序列
+	empty := prometheustest.NewServer(func(query prometheustest.Query) ([]prometheus.Series, error) {
+		return nil, nil
+	})
+	defer empty.Close()
+	source.Client = prometheus.NewClient(empty.URL, time.Second)
+	cpu, memory, err := source.WorkloadHistory(context.Background(), newTestHPAModifier(), testSelector, time.Now().Add(-time.Hour), time.Now(), time.Minute)
+	assert.NoError(t, err)
+	assert.Empty(t, cpu)
+	assert.Empty(t, memory)
+}
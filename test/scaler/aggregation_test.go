@@ -0,0 +1,91 @@
+package scaler_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakescale "k8s.io/client-go/scale/fake"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+	"yemo.info/auto-scaling-system/internal/scaler"
+)
+
+// newSkewedPodMetrics 四个 Pod 的 app 容器分别用 100m、200m、300m 和 1 核，每个 Pod 的 istio-proxy 用 500m
+func newSkewedPodMetrics() *metricsv1beta1.PodMetricsList {
+	podMetrics := &metricsv1beta1.PodMetricsList{}
+	for i, cpu := range []string{"100m", "200m", "300m", "1"} {
+		podMetrics.Items = append(podMetrics.Items, metricsv1beta1.PodMetrics{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod-%d", i), Namespace: "default"},
+			Containers: []metricsv1beta1.ContainerMetrics{
+				newContainerMetrics("app", cpu, "1Gi"),
+				newContainerMetrics("istio-proxy", "500m", "128Mi"),
+			},
+		})
+	}
+	return podMetrics
+}
+
+func TestCollectMetricsAggregation(t *testing.T) {
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(newSkewedPodMetrics(), nil)
+	manager := scaler.NewScalingManager(newTestKubeClient(), &fakescale.FakeScaleClient{}, newTestRESTMapper(), mockMetricsClient, "")
+
+	hpa := createTestHPAModifier()
+	hpa.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}}
+	hpa.Spec.Containers = &autoscalingv1.ContainerSelector{Exclude: []string{"istio-proxy"}}
+
+	for aggregation, expected := range map[autoscalingv1.AggregationFunction]float64{
+		autoscalingv1.AggregationMean:   0.4,
+		autoscalingv1.AggregationMax:    1.0,
+		autoscalingv1.AggregationMedian: 0.25,
+		autoscalingv1.AggregationP90:    0.79,
+		autoscalingv1.AggregationP95:    0.895,
+	} {
+		hpa.Spec.Aggregation = aggregation
+		cpuUsage, memoryUsage, err := manager.CollectMetrics(context.Background(), hpa)
+		assert.NoError(t, err)
+		assert.InDelta(t, expected, cpuUsage, 1e-9, aggregation)
+		assert.InDelta(t, 1.0, memoryUsage, 1e-9, aggregation)
+	}
+
+	// 计入 sidecar 时每个 Pod 多 500m
+	hpa.Spec.Containers = nil
+	hpa.Spec.Aggregation = autoscalingv1.AggregationMax
+	cpuUsage, _, err := manager.CollectMetrics(context.Background(), hpa)
+	assert.NoError(t, err)
+	assert.InDelta(t, 1.5, cpuUsage, 1e-9)
+
+	// 没有容器匹配时报错
+	hpa.Spec.Containers = &autoscalingv1.ContainerSelector{Include: []string{"worker"}}
+	_, _, err = manager.CollectMetrics(context.Background(), hpa)
+	assert.True(t, scaler.IsTargetError(err))
+	assert.Contains(t, err.Error(), "spec.containers")
+}
+
+func TestCollectMetricsUtilizationAggregation(t *testing.T) {
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(newUtilizationPodMetrics(), nil)
+	kubeClient := newTestKubeClient(newRequestPod("pod-a", true), newRequestPod("pod-b", false))
+	manager := scaler.NewScalingManager(kubeClient, &fakescale.FakeScaleClient{}, newTestRESTMapper(), mockMetricsClient, "")
+
+	// 排除 sidecar 后无需 IgnoreContainer；pod-a 利用率 50%，pod-b 100%
+	hpa := createTestHPAModifier()
+	hpa.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}}
+	hpa.Spec.ThresholdMode = autoscalingv1.ThresholdModeUtilization
+	hpa.Spec.Containers = &autoscalingv1.ContainerSelector{Exclude: []string{"sidecar"}}
+	hpa.Spec.Aggregation = autoscalingv1.AggregationMax
+
+	cpuUtilization, memoryUtilization, err := manager.CollectMetrics(context.Background(), hpa)
+	assert.NoError(t, err)
+	assert.InDelta(t, 1.0, cpuUtilization, 1e-9)
+	assert.InDelta(t, 1.0, memoryUtilization, 1e-9)
+
+	hpa.Spec.Aggregation = autoscalingv1.AggregationMedian
+	cpuUtilization, _, err = manager.CollectMetrics(context.Background(), hpa)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.75, cpuUtilization, 1e-9)
+}
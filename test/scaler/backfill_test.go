@@ -0,0 +1,101 @@
+package scaler_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	k8sautoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakescale "k8s.io/client-go/scale/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+	"yemo.info/auto-scaling-system/internal/scaler"
+)
+
+// periodicHistorySource 返回周期为 1 小时的正弦负载
+type periodicHistorySource struct {
+	calls    int
+	selector string
+}
+
+func (s *periodicHistorySource) WorkloadHistory(ctx context.Context, hpa *autoscalingv1.HPAModifier, selector labels.Selector,
+	start, end time.Time, step time.Duration) ([]scaler.Sample, []scaler.Sample, error) {
+	s.calls++
+	s.selector = selector.String()
+	var cpu, memory []scaler.Sample
+	for ts := start; ts.Before(end); ts = ts.Add(step) {
+		phase := 2 * math.Pi * float64(ts.Unix()%3600) / 3600
+		cpu = append(cpu, scaler.Sample{Timestamp: ts, Value: 0.5 + 0.3*math.Sin(phase)})
+		memory = append(memory, scaler.Sample{Timestamp: ts, Value: 1})
+	}
+	return cpu, memory, nil
+}
+
+func TestScaleWorkloadBackfillsHistory(t *testing.T) {
+	predictorServer := newPredictorServer([]float64{0.7})
+	defer predictorServer.Close()
+
+	scaleClient := &fakescale.FakeScaleClient{}
+	scaleClient.AddReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &k8sautoscalingv1.Scale{
+			Spec:   k8sautoscalingv1.ScaleSpec{Replicas: 1},
+			Status: k8sautoscalingv1.ScaleStatus{Replicas: 1, Selector: "app=nginx"},
+		}, nil
+	})
+	scaleClient.AddReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, action.(k8stesting.UpdateAction).GetObject(), nil
+	})
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(createTestPodMetrics(), nil)
+
+	source := &periodicHistorySource{}
+	manager := scaler.NewScalingManager(newTestKubeClient(), scaleClient, newTestRESTMapper(), mockMetricsClient, predictorServer.URL)
+	manager.HistorySource = source
+
+	// 第一次调谐就能识别出周期性
+	hpa := createTestHPAModifier()
+	assert.NoError(t, manager.ScaleWorkload(context.Background(), hpa))
+	assert.Equal(t, "Periodic", hpa.Status.WorkloadPattern)
+	// 按解析出的标签选择器回填
+	assert.Equal(t, "app=nginx", source.selector)
+
+	// 每个工作负载只回填一次
+	assert.NoError(t, manager.ScaleWorkload(context.Background(), hpa))
+	assert.Equal(t, 1, source.calls)
+}
+
+func TestScaleWorkloadSkipsBackfillInUtilizationMode(t *testing.T) {
+	predictorServer := newPredictorServer([]float64{0.7})
+	defer predictorServer.Close()
+
+	scaleClient := &fakescale.FakeScaleClient{}
+	scaleClient.AddReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &k8sautoscalingv1.Scale{
+			Spec:   k8sautoscalingv1.ScaleSpec{Replicas: 2},
+			Status: k8sautoscalingv1.ScaleStatus{Replicas: 2, Selector: "app=nginx"},
+		}, nil
+	})
+	scaleClient.AddReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, action.(k8stesting.UpdateAction).GetObject(), nil
+	})
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(newUtilizationPodMetrics(), nil)
+	kubeClient := newTestKubeClient(newRequestPod("pod-a", true), newRequestPod("pod-b", false))
+
+	source := &periodicHistorySource{}
+	manager := scaler.NewScalingManager(kubeClient, scaleClient, newTestRESTMapper(), mockMetricsClient, predictorServer.URL)
+	manager.HistorySource = source
+
+	// 回填的是绝对用量，不能写入利用率的历史
+	hpa := createTestHPAModifier()
+	hpa.Spec.ThresholdMode = autoscalingv1.ThresholdModeUtilization
+	hpa.Spec.MissingRequests = autoscalingv1.MissingRequestsIgnoreContainer
+	assert.NoError(t, manager.ScaleWorkload(context.Background(), hpa))
+	assert.Equal(t, 0, source.calls)
+	assert.NotEqual(t, "Periodic", hpa.Status.WorkloadPattern)
+}
@@ -0,0 +1,144 @@
+package scaler_test
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8sautoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakescale "k8s.io/client-go/scale/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+	"yemo.info/auto-scaling-system/internal/scaler"
+)
+
+// behaviorFixture 预测负载可调、副本数会随更新变化的测试环境
+type behaviorFixture struct {
+	manager  *scaler.ScalingManager
+	load     atomic.Uint64
+	replicas int32
+}
+
+func newBehaviorFixture(t *testing.T, replicas int32) *behaviorFixture {
+	f := &behaviorFixture{replicas: replicas}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		load := math.Float64frombits(f.load.Load())
+		_ = json.NewEncoder(w).Encode(scaler.PredictionResponse{Values: []float64{load}})
+	}))
+	t.Cleanup(server.Close)
+
+	scaleClient := &fakescale.FakeScaleClient{}
+	scaleClient.AddReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &k8sautoscalingv1.Scale{
+			Spec:   k8sautoscalingv1.ScaleSpec{Replicas: f.replicas},
+			Status: k8sautoscalingv1.ScaleStatus{Replicas: f.replicas, Selector: "app=nginx"},
+		}, nil
+	})
+	scaleClient.AddReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		obj := action.(k8stesting.UpdateAction).GetObject().(*k8sautoscalingv1.Scale)
+		f.replicas = obj.Spec.Replicas
+		return true, obj, nil
+	})
+
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(createTestPodMetrics(), nil)
+
+	f.manager = scaler.NewScalingManager(newTestKubeClient(), scaleClient, newTestRESTMapper(), mockMetricsClient, server.URL)
+	return f
+}
+
+// reconcile 以给定的每副本预测负载执行一次伸缩
+func (f *behaviorFixture) reconcile(t *testing.T, hpa *autoscalingv1.HPAModifier, load float64) {
+	f.load.Store(math.Float64bits(load))
+	hpa.Status.CurrentReplicas = f.replicas
+	assert.NoError(t, f.manager.ScaleWorkload(context.Background(), hpa))
+}
+
+func selectPolicy(policy autoscalingv2.ScalingPolicySelect) *autoscalingv2.ScalingPolicySelect {
+	return &policy
+}
+
+func stabilizationWindow(seconds int32) *int32 {
+	return &seconds
+}
+
+func TestBehaviorScaleUpPolicy(t *testing.T) {
+	f := newBehaviorFixture(t, 2)
+	hpa := createTestHPAModifier()
+	hpa.Spec.MaxReplicas = 20
+	hpa.Spec.Behavior = &autoscalingv2.HorizontalPodAutoscalerBehavior{
+		ScaleUp: &autoscalingv2.HPAScalingRules{
+			Policies: []autoscalingv2.HPAScalingPolicy{
+				{Type: autoscalingv2.PodsScalingPolicy, Value: 2, PeriodSeconds: 60},
+			},
+		},
+	}
+
+	// 负载 3.5 / 阈值 0.7 = 5 倍，期望 10 个副本，每 60 秒最多增加 2 个
+	f.reconcile(t, hpa, 3.5)
+	assert.Equal(t, int32(4), f.replicas)
+	condition := meta.FindStatusCondition(hpa.Status.Conditions, autoscalingv1.ConditionScalingLimited)
+	assert.Equal(t, autoscalingv1.ReasonScaleUpLimit, condition.Reason)
+
+	// 同一周期内已经增加了 2 个，不能继续扩容
+	f.reconcile(t, hpa, 1.75)
+	assert.Equal(t, int32(4), f.replicas)
+
+	// 禁用扩容时保持不变
+	hpa.Spec.Behavior.ScaleUp.SelectPolicy = selectPolicy(autoscalingv2.DisabledPolicySelect)
+	f.reconcile(t, hpa, 3.5)
+	assert.Equal(t, int32(4), f.replicas)
+}
+
+func TestBehaviorScaleDownPolicy(t *testing.T) {
+	f := newBehaviorFixture(t, 8)
+	hpa := createTestHPAModifier()
+	hpa.Spec.Behavior = &autoscalingv2.HorizontalPodAutoscalerBehavior{
+		ScaleDown: &autoscalingv2.HPAScalingRules{
+			StabilizationWindowSeconds: stabilizationWindow(0),
+			Policies: []autoscalingv2.HPAScalingPolicy{
+				{Type: autoscalingv2.PercentScalingPolicy, Value: 50, PeriodSeconds: 60},
+				{Type: autoscalingv2.PodsScalingPolicy, Value: 1, PeriodSeconds: 60},
+			},
+			SelectPolicy: selectPolicy(autoscalingv2.MinChangePolicySelect),
+		},
+	}
+
+	// 预测负载骤降，期望 1 个副本；Min 选择变化最小的策略，一次只缩容 1 个
+	f.reconcile(t, hpa, 0.07)
+	assert.Equal(t, int32(7), f.replicas)
+	condition := meta.FindStatusCondition(hpa.Status.Conditions, autoscalingv1.ConditionScalingLimited)
+	assert.Equal(t, autoscalingv1.ReasonScaleDownLimit, condition.Reason)
+
+	// Max 选择变化最大的策略：周期起点 8 个副本的 50%
+	hpa.Spec.Behavior.ScaleDown.SelectPolicy = selectPolicy(autoscalingv2.MaxChangePolicySelect)
+	f.reconcile(t, hpa, 0.07)
+	assert.Equal(t, int32(4), f.replicas)
+}
+
+func TestBehaviorScaleDownStabilization(t *testing.T) {
+	f := newBehaviorFixture(t, 2)
+	hpa := createTestHPAModifier()
+	// 未配置的方向使用默认规则：缩容稳定窗口 5 分钟
+	hpa.Spec.Behavior = &autoscalingv2.HorizontalPodAutoscalerBehavior{}
+
+	// 负载 1.75 / 阈值 0.7，从 2 扩到 5
+	f.reconcile(t, hpa, 1.75)
+	assert.Equal(t, int32(5), f.replicas)
+
+	// 一次错误的低预测不会立即缩容
+	f.reconcile(t, hpa, 0.07)
+	assert.Equal(t, int32(5), f.replicas)
+	condition := meta.FindStatusCondition(hpa.Status.Conditions, autoscalingv1.ConditionAbleToScale)
+	assert.Equal(t, autoscalingv1.ReasonScaleDownStabilized, condition.Reason)
+}
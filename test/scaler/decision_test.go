@@ -0,0 +1,109 @@
+package scaler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+
+	"yemo.info/auto-scaling-system/internal/scaler"
+)
+
+func TestScaleWorkloadRecordsDecision(t *testing.T) {
+	// 预测的 CPU 和内存峰值都为 7，分别是阈值的 10 倍和 8.75 倍
+	predictorServer := newPredictorServer([]float64{0.5, 7.0, 0.5})
+	defer predictorServer.Close()
+
+	replicas := int32(2)
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(createTestPodMetrics(), nil)
+	manager := scaler.NewScalingManager(newTestKubeClient(), newLiveScaleClient(&replicas), newTestRESTMapper(),
+		mockMetricsClient, predictorServer.URL)
+
+	hpa := createTestHPAModifier()
+	assert.NoError(t, manager.ScaleWorkload(context.Background(), hpa))
+	assert.Equal(t, int32(10), replicas)
+
+	decision := manager.LastDecision("default", "test-hpa")
+	if !assert.NotNil(t, decision) {
+		return
+	}
+	assert.Equal(t, scaler.ActionScaleUp, decision.Action)
+	assert.True(t, decision.Changed)
+	assert.Equal(t, int32(2), decision.Inputs.CurrentReplicas)
+	assert.Equal(t, int32(10), decision.DesiredReplicas)
+	assert.Equal(t, "Stable", decision.Inputs.Pattern)
+	assert.Equal(t, "predictor", decision.Inputs.Forecast.Source)
+	assert.True(t, decision.Inputs.Forecast.Available)
+	if assert.Len(t, decision.Inputs.Metrics, 2) {
+		assert.Equal(t, scaler.MetricInput{Name: "cpu", Current: 0.5, Peak: 7, Target: 0.7}, decision.Inputs.Metrics[0])
+	}
+	if assert.Len(t, decision.Proposals, 2) {
+		assert.Equal(t, int32(20), decision.Proposals[0].Replicas)
+		assert.Equal(t, int32(18), decision.Proposals[1].Replicas)
+	}
+	if assert.Len(t, decision.Adjustments, 1) {
+		assert.Equal(t, scaler.StageReplicaLimits, decision.Adjustments[0].Stage)
+		assert.Equal(t, int32(20), decision.Adjustments[0].From)
+		assert.Equal(t, int32(10), decision.Adjustments[0].To)
+	}
+	assert.Contains(t, decision.Summary(), "ScaleUp: 2 -> 10 replicas")
+
+	// 调试端点按命名空间过滤，只接受 GET
+	recorder := httptest.NewRecorder()
+	manager.Decisions.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/decisions?namespace=default", nil))
+	var decisions []scaler.ScalingDecision
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &decisions))
+	if assert.Len(t, decisions, 1) {
+		assert.Equal(t, scaler.ActionScaleUp, decisions[0].Action)
+	}
+	recorder = httptest.NewRecorder()
+	manager.Decisions.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/decisions?namespace=other", nil))
+	assert.JSONEq(t, "[]", recorder.Body.String())
+	recorder = httptest.NewRecorder()
+	manager.Decisions.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/debug/decisions", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+
+	// 删除 HPAModifier 后不再保留决策
+	assert.NoError(t, manager.Forget(context.Background(), "default", "test-hpa"))
+	assert.Nil(t, manager.LastDecision("default", "test-hpa"))
+}
+
+func TestScaleWorkloadRecordsFailedDecision(t *testing.T) {
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").
+		Return((*metricsv1beta1.PodMetricsList)(nil), errors.New("metrics API unavailable"))
+	replicas := int32(2)
+	manager := scaler.NewScalingManager(newTestKubeClient(), newLiveScaleClient(&replicas), newTestRESTMapper(),
+		mockMetricsClient, "")
+
+	hpa := createTestHPAModifier()
+	assert.Error(t, manager.ScaleWorkload(context.Background(), hpa))
+	decision := manager.LastDecision("default", "test-hpa")
+	if assert.NotNil(t, decision) {
+		assert.Equal(t, scaler.ActionFailed, decision.Action)
+		assert.Contains(t, decision.Message, "metrics API unavailable")
+	}
+}
+
+func TestDecisionNeedsEvent(t *testing.T) {
+	delayed := &scaler.ScalingDecision{Action: scaler.ActionDelayed, Reason: "BackoffBoth"}
+	// 第一次出现的动作创建 Event，之后相同的动作和原因不再重复
+	assert.True(t, delayed.NeedsEvent(nil))
+	assert.False(t, delayed.NeedsEvent(&scaler.ScalingDecision{Action: scaler.ActionDelayed, Reason: "BackoffBoth"}))
+	assert.True(t, delayed.NeedsEvent(&scaler.ScalingDecision{Action: scaler.ActionScaleUp, Reason: "SucceededRescale"}))
+
+	// 未修改 HPA 的 ModifyNativeHPA 不重复创建 Event，修改时总是创建
+	modify := &scaler.ScalingDecision{Action: scaler.ActionModifyNativeHPA, Reason: "ModifiedNativeHPA"}
+	assert.False(t, modify.NeedsEvent(modify))
+	modify.Changed = true
+	assert.True(t, modify.NeedsEvent(modify))
+
+	// 副本数不变时不创建 Event
+	assert.False(t, (&scaler.ScalingDecision{Action: scaler.ActionNone}).NeedsEvent(delayed))
+}
@@ -0,0 +1,103 @@
+package scaler_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8sautoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakescale "k8s.io/client-go/scale/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+	"yemo.info/auto-scaling-system/internal/scaler"
+)
+
+// seasonalSeries 生成周期为 period、带缓慢上升趋势的正弦序列
+func seasonalSeries(n, period int) []float64 {
+	data := make([]float64, n)
+	for i := range data {
+		data[i] = 1.0 + 0.01*float64(i) + 0.5*math.Sin(2*math.Pi*float64(i)/float64(period))
+	}
+	return data
+}
+
+func TestHoltWintersForecast(t *testing.T) {
+	history := seasonalSeries(48, 12)
+	expected := seasonalSeries(54, 12)[48:]
+
+	forecaster := scaler.NewHoltWintersForecaster()
+	forecast, err := forecaster.Forecast(history, 6)
+	assert.NoError(t, err)
+	assert.Len(t, forecast, 6)
+	for i := range forecast {
+		assert.InDelta(t, expected[i], forecast[i], 0.15, "step %d", i+1)
+	}
+
+	// 历史数据不足两个周期时退化为线性趋势
+	forecast, err = forecaster.Forecast([]float64{1, 2, 3}, 2)
+	assert.NoError(t, err)
+	assert.Len(t, forecast, 2)
+	assert.True(t, forecast[1] > forecast[0])
+
+	_, err = forecaster.Forecast(nil, 1)
+	assert.Error(t, err)
+}
+
+func TestARIMAForecast(t *testing.T) {
+	// 线性增长的序列，一阶差分后为常数
+	history := make([]float64, 20)
+	for i := range history {
+		history[i] = 0.5 + 0.1*float64(i)
+	}
+
+	forecaster := scaler.NewARIMAForecaster()
+	forecast, err := forecaster.Forecast(history, 3)
+	assert.NoError(t, err)
+	assert.Len(t, forecast, 3)
+	for i, v := range forecast {
+		assert.InDelta(t, 0.5+0.1*float64(20+i), v, 1e-6)
+	}
+
+	// 只有一个点时保持不变
+	forecast, err = forecaster.Forecast([]float64{0.4}, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{0.4, 0.4}, forecast)
+}
+
+func TestScaleWorkloadWithBuiltinForecaster(t *testing.T) {
+	scaleClient := &fakescale.FakeScaleClient{}
+	scaleClient.AddReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &k8sautoscalingv1.Scale{
+			Spec:   k8sautoscalingv1.ScaleSpec{Replicas: 1},
+			Status: k8sautoscalingv1.ScaleStatus{Replicas: 1, Selector: "app=nginx"},
+		}, nil
+	})
+	var updatedReplicas int32
+	scaleClient.AddReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		obj := action.(k8stesting.UpdateAction).GetObject().(*k8sautoscalingv1.Scale)
+		updatedReplicas = obj.Spec.Replicas
+		return true, obj, nil
+	})
+
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(createTestPodMetrics(), nil)
+
+	// 预测服务地址为空，内置预测器不应访问它
+	manager := scaler.NewScalingManager(newTestKubeClient(), scaleClient, newTestRESTMapper(), mockMetricsClient, "")
+
+	for _, forecaster := range []autoscalingv1.ForecasterType{autoscalingv1.ForecasterHoltWinters, autoscalingv1.ForecasterARIMA} {
+		hpa := createTestHPAModifier()
+		hpa.Spec.Forecaster = forecaster
+		hpa.Spec.CPUThreshold = 0.25
+		hpa.Status.LastScaledTime = &metav1.Time{}
+
+		err := manager.ScaleWorkload(context.Background(), hpa)
+		assert.NoError(t, err, string(forecaster))
+		// 当前 CPU 0.5 核 / 阈值 0.25 = 2 倍
+		assert.Equal(t, int32(2), updatedReplicas, string(forecaster))
+	}
+}
@@ -0,0 +1,123 @@
+package scaler_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	k8sautoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakescale "k8s.io/client-go/scale/fake"
+	k8stesting "k8s.io/client-go/testing"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+	"yemo.info/auto-scaling-system/internal/scaler"
+)
+
+// newTimedPodMetrics 返回带时间戳和统计窗口的 Pod 指标
+func newTimedPodMetrics(name, cpu, memory string, timestamp time.Time, window time.Duration) metricsv1beta1.PodMetrics {
+	return metricsv1beta1.PodMetrics{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Timestamp:  metav1.NewTime(timestamp),
+		Window:     metav1.Duration{Duration: window},
+		Containers: []metricsv1beta1.ContainerMetrics{newContainerMetrics("app", cpu, memory)},
+	}
+}
+
+func TestCollectMetricsSkipsStaleSamples(t *testing.T) {
+	now := time.Now()
+	fresh := now.Add(-10 * time.Second)
+	podMetrics := &metricsv1beta1.PodMetricsList{Items: []metricsv1beta1.PodMetrics{
+		newTimedPodMetrics("fresh", "500m", "1Gi", fresh, 30*time.Second),
+		newTimedPodMetrics("old", "2", "4Gi", now.Add(-10*time.Minute), 30*time.Second),
+		newTimedPodMetrics("wide-window", "2", "4Gi", fresh, 10*time.Minute),
+	}}
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(podMetrics, nil)
+	manager := scaler.NewScalingManager(newTestKubeClient(), &fakescale.FakeScaleClient{}, newTestRESTMapper(), mockMetricsClient, "")
+
+	hpa := createTestHPAModifier()
+	hpa.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}}
+
+	cpuUsage, _, err := manager.CollectMetrics(context.Background(), hpa)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.5, cpuUsage, 1e-9)
+	assert.Equal(t, int32(1), hpa.Status.MetricPods)
+	assert.Equal(t, int32(2), hpa.Status.ExcludedPods.Stale)
+	assert.True(t, fresh.Equal(hpa.Status.LastSampleTime.Time))
+
+	// 放宽限制后全部计入
+	hpa.Spec.MetricFreshness = &autoscalingv1.MetricFreshness{MaxAgeSeconds: 3600, MaxWindowSeconds: 3600}
+	_, _, err = manager.CollectMetrics(context.Background(), hpa)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), hpa.Status.MetricPods)
+
+	// 所有样本都过期时报错
+	hpa.Spec.MetricFreshness = &autoscalingv1.MetricFreshness{MaxAgeSeconds: 1}
+	_, _, err = manager.CollectMetrics(context.Background(), hpa)
+	var staleErr *scaler.StaleMetricsError
+	assert.True(t, errors.As(err, &staleErr))
+	assert.Equal(t, 3, staleErr.Pods)
+}
+
+func TestScaleWorkloadMetricsStale(t *testing.T) {
+	predictorServer := newPredictorServer([]float64{0.7})
+	defer predictorServer.Close()
+
+	scaleClient := &fakescale.FakeScaleClient{}
+	scaleClient.AddReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &k8sautoscalingv1.Scale{
+			Spec:   k8sautoscalingv1.ScaleSpec{Replicas: 1},
+			Status: k8sautoscalingv1.ScaleStatus{Replicas: 1, Selector: "app=nginx"},
+		}, nil
+	})
+	scaleClient.AddReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, action.(k8stesting.UpdateAction).GetObject(), nil
+	})
+
+	// metrics-server 未更新时第二次返回相同时间戳的样本
+	sampleTime := time.Now().Add(-15 * time.Second)
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(&metricsv1beta1.PodMetricsList{
+		Items: []metricsv1beta1.PodMetrics{newTimedPodMetrics("pod-a", "500m", "1Gi", sampleTime, 30*time.Second)},
+	}, nil).Once()
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(&metricsv1beta1.PodMetricsList{
+		Items: []metricsv1beta1.PodMetrics{newTimedPodMetrics("pod-a", "500m", "3Gi", sampleTime, 30*time.Second)},
+	}, nil).Once()
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(&metricsv1beta1.PodMetricsList{
+		Items: []metricsv1beta1.PodMetrics{newTimedPodMetrics("pod-a", "500m", "1Gi", time.Now().Add(-time.Hour), 30*time.Second)},
+	}, nil).Once()
+
+	manager := scaler.NewScalingManager(newTestKubeClient(), scaleClient, newTestRESTMapper(), mockMetricsClient, predictorServer.URL)
+	persister := &scaler.FilePersister{Dir: t.TempDir()}
+	manager.HistoryPersister = persister
+	hpa := createTestHPAModifier()
+
+	assert.NoError(t, manager.ScaleWorkload(context.Background(), hpa))
+	condition := meta.FindStatusCondition(hpa.Status.Conditions, autoscalingv1.ConditionMetricsStale)
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+
+	// 重复的样本不写入历史，内存历史仍为第一次的 1GiB
+	assert.NoError(t, manager.ScaleWorkload(context.Background(), hpa))
+	condition = meta.FindStatusCondition(hpa.Status.Conditions, autoscalingv1.ConditionMetricsStale)
+	assert.Equal(t, metav1.ConditionTrue, condition.Status)
+	assert.Equal(t, autoscalingv1.ReasonDuplicateSample, condition.Reason)
+	assert.NoError(t, manager.SaveHistory(context.Background()))
+	snapshots, err := persister.Load(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, snapshots, 1) {
+		assert.Equal(t, []float64{1}, snapshots[0].Series["default/nginx-deployment#memory"].Values)
+	}
+
+	// 所有样本都过期时不伸缩
+	err = manager.ScaleWorkload(context.Background(), hpa)
+	assert.Error(t, err)
+	condition = meta.FindStatusCondition(hpa.Status.Conditions, autoscalingv1.ConditionMetricsStale)
+	assert.Equal(t, autoscalingv1.ReasonStaleSamples, condition.Reason)
+	assert.True(t, meta.IsStatusConditionFalse(hpa.Status.Conditions, autoscalingv1.ConditionMetricsAvailable))
+}
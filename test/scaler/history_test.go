@@ -0,0 +1,70 @@
+package scaler_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"yemo.info/auto-scaling-system/internal/scaler"
+)
+
+func TestHistoryStoreResample(t *testing.T) {
+	store := scaler.NewHistoryStore(time.Hour, 5*time.Minute)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// 同一间隔内的多次调谐取平均
+	store.Add("default/web", start, 1)
+	store.Add("default/web", start.Add(10*time.Second), 3)
+	// 中间缺少两个间隔
+	store.Add("default/web", start.Add(15*time.Minute), 5)
+	// 乱序写入的回填数据
+	store.Add("default/web", start.Add(20*time.Minute+time.Second), 6)
+	store.Add("default/web", start.Add(5*time.Minute), 4)
+
+	assert.Equal(t, []float64{2, 4, 4.5, 5, 6}, store.Values("default/web"))
+	samples := store.Samples("default/web")
+	assert.Equal(t, start.Add(10*time.Minute), samples[2].Timestamp)
+
+	// 超出窗口的旧数据被丢弃
+	store.Add("default/web", start.Add(70*time.Minute), 7)
+	values := store.Values("default/web")
+	assert.Len(t, values, 12)
+	assert.Equal(t, 5.0, values[0])
+}
+
+func TestHistoryStoreDeleteWorkload(t *testing.T) {
+	store := scaler.NewHistoryStore(time.Hour, time.Minute)
+	now := time.Now()
+	store.Add("default/web", now, 1)
+	store.Add("default/web#memory", now, 1)
+	store.Add("default/web-2", now, 1)
+
+	store.DeleteWorkload("default/web")
+	assert.Equal(t, []string{"default/web-2"}, store.Keys())
+	assert.Empty(t, store.Values("default/web"))
+}
+
+func TestHistoryStoreConcurrentAccess(t *testing.T) {
+	store := scaler.NewHistoryStore(time.Hour, time.Second)
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			key := fmt.Sprintf("default/web-%d", w%2)
+			for i := 0; i < 100; i++ {
+				store.Add(key, start.Add(time.Duration(i)*time.Second), float64(i))
+				_ = store.Values(key)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	assert.Len(t, store.Values("default/web-0"), 100)
+	assert.Len(t, store.Values("default/web-1"), 100)
+}
@@ -7,8 +7,14 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/scale"
 	"k8s.io/client-go/tools/clientcmd"
 	metrics "k8s.io/metrics/pkg/client/clientset/versioned"
 
@@ -17,6 +23,15 @@ import (
 	"yemo.info/auto-scaling-system/internal/scaler"
 )
 
+// newScaleClient 基于 discovery 创建 REST mapper 和 /scale 客户端
+func newScaleClient(t *testing.T, config *rest.Config, kubeClient kubernetes.Interface) (meta.RESTMapper, scale.ScalesGetter) {
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(kubeClient.Discovery()))
+	scaleClient, err := scale.NewForConfig(config, mapper, dynamic.LegacyAPIPathResolverFunc,
+		scale.NewDiscoveryScaleKindResolver(kubeClient.Discovery()))
+	assert.NoError(t, err, "应能创建 Scale 客户端")
+	return mapper, scaleClient
+}
+
 func TestCollectMetricsWithRealCluster(t *testing.T) {
 	// 1. 创建真实的客户端连接
 	config, err := clientcmd.BuildConfigFromFlags("", clientcmd.NewDefaultClientConfigLoadingRules().GetDefaultFilename())
@@ -38,6 +53,7 @@ func TestCollectMetricsWithRealCluster(t *testing.T) {
 		KubeClient:    kubeClient,
 		MetricsClient: realMetricsClient,
 	}
+	manager.RESTMapper, manager.ScaleClient = newScaleClient(t, config, kubeClient)
 
 	// 4. 创建 HPAModifier 配置
 	hpa := &autoscalingv1.HPAModifier{
@@ -140,6 +156,7 @@ func TestScaleWorkloadWithRealCluster(t *testing.T) {
 		KubeClient:    kubeClient,
 		MetricsClient: realMetricsClient,
 	}
+	manager.RESTMapper, manager.ScaleClient = newScaleClient(t, config, kubeClient)
 
 	// 4. 创建测试用的 HPAModifier
 	hpa := &autoscalingv1.HPAModifier{
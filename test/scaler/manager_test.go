@@ -2,15 +2,29 @@ package scaler_test
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	appsv1 "k8s.io/api/apps/v1"
+	k8sautoscalingv1 "k8s.io/api/autoscaling/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
+	fakescale "k8s.io/client-go/scale/fake"
+	k8stesting "k8s.io/client-go/testing"
 	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
-	"testing"
+
 	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+	"yemo.info/auto-scaling-system/internal/predictor"
 	"yemo.info/auto-scaling-system/internal/scaler"
 )
 
@@ -19,8 +33,8 @@ type MockMetricsClient struct {
 	mock.Mock
 }
 
-func (m *MockMetricsClient) GetPodMetrics(namespace string) (*metricsv1beta1.PodMetricsList, error) {
-	args := m.Called(namespace)
+func (m *MockMetricsClient) GetPodMetrics(ctx context.Context, namespace string, selector labels.Selector) (*metricsv1beta1.PodMetricsList, error) {
+	args := m.Called(namespace, selector.String())
 	return args.Get(0).(*metricsv1beta1.PodMetricsList), args.Error(1)
 }
 
@@ -83,7 +97,7 @@ func TestCollectMetrics(t *testing.T) {
 	podMetrics := createTestPodMetrics()
 
 	// 设置模拟行为
-	mockMetricsClient.On("GetPodMetrics", "default").Return(podMetrics, nil)
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(podMetrics, nil)
 
 	// 创建伸缩管理器，使用自定义的 mock metrics client
 	manager := &scaler.ScalingManager{
@@ -91,8 +105,9 @@ func TestCollectMetrics(t *testing.T) {
 		MetricsClient: mockMetricsClient,
 	}
 
-	// 创建测试 HPAModifier
+	// 创建测试 HPAModifier，显式指定 Pod 选择器
 	hpa := createTestHPAModifier()
+	hpa.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}}
 
 	// 测试收集指标
 	cpuUsage, memoryUsage, err := manager.CollectMetrics(context.Background(), hpa)
@@ -137,3 +152,307 @@ func TestCalculateDesiredReplicas(t *testing.T) {
 func TestScaleWorkload(t *testing.T) {
 
 }
+
+func TestCollectMetricsSelectorFromScaleStatus(t *testing.T) {
+	scaleClient := &fakescale.FakeScaleClient{}
+	scaleClient.AddReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &k8sautoscalingv1.Scale{
+			Status: k8sautoscalingv1.ScaleStatus{Replicas: 2, Selector: "app=api,tier=backend"},
+		}, nil
+	})
+
+	// 只有选择器匹配的 Pod 由服务端返回，"api-gateway" 的 Pod 不会被计入
+	podMetrics := createTestPodMetrics()
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=api,tier=backend").Return(podMetrics, nil)
+
+	manager := scaler.NewScalingManager(newTestKubeClient(), scaleClient, newTestRESTMapper(), mockMetricsClient, "")
+	hpa := createTestHPAModifier()
+	hpa.Spec.TargetRef.Name = "api"
+
+	cpuUsage, memoryUsage, err := manager.CollectMetrics(context.Background(), hpa)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.5, cpuUsage, 1e-9)
+	assert.InDelta(t, 1.0, memoryUsage, 1e-9)
+	mockMetricsClient.AssertExpectations(t)
+
+	// scale status 中没有选择器时报错，提示设置 spec.selector
+	scaleClient.PrependReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &k8sautoscalingv1.Scale{}, nil
+	})
+	_, _, err = manager.CollectMetrics(context.Background(), hpa)
+	assert.True(t, scaler.IsTargetError(err))
+	assert.Contains(t, err.Error(), "spec.selector")
+}
+
+// fakeMetricsSource 对所有工作负载返回同一个 MetricsClient
+type fakeMetricsSource struct {
+	client scaler.MetricsClient
+}
+
+func (f *fakeMetricsSource) ForWorkload(hpa *autoscalingv1.HPAModifier) (scaler.MetricsClient, error) {
+	return f.client, nil
+}
+
+func TestCollectMetricsPrometheusSource(t *testing.T) {
+	defaultClient := &MockMetricsClient{}
+	prometheusClient := &MockMetricsClient{}
+	prometheusClient.On("GetPodMetrics", "default", "app=nginx").Return(createTestPodMetrics(), nil)
+
+	manager := &scaler.ScalingManager{KubeClient: fake.NewSimpleClientset(), MetricsClient: defaultClient}
+	hpa := createTestHPAModifier()
+	hpa.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}}
+	hpa.Spec.MetricsSource = autoscalingv1.MetricsSourcePrometheus
+
+	// 控制器未配置 Prometheus
+	_, _, err := manager.CollectMetrics(context.Background(), hpa)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "--prometheus-url")
+
+	manager.PrometheusMetrics = &fakeMetricsSource{client: prometheusClient}
+	cpuUsage, _, err := manager.CollectMetrics(context.Background(), hpa)
+	assert.NoError(t, err)
+	assert.True(t, cpuUsage > 0)
+	prometheusClient.AssertExpectations(t)
+	defaultClient.AssertNotCalled(t, "GetPodMetrics", mock.Anything, mock.Anything)
+}
+
+// newPredictorServer 启动一个对所有指标返回固定预测值的预测服务
+func newPredictorServer(values []float64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(scaler.PredictionResponse{Values: values})
+	}))
+}
+
+// newTestRESTMapper 创建包含 Deployment、StatefulSet 和 ConfigMap 的 REST mapper
+func newTestRESTMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("StatefulSet"), meta.RESTScopeNamespace)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	return mapper
+}
+
+// newTestKubeClient 创建 discovery 中声明了 /scale 子资源的 fake 客户端
+func newTestKubeClient(objects ...runtime.Object) *fake.Clientset {
+	kubeClient := fake.NewSimpleClientset(objects...)
+	kubeClient.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "deployments"}, {Name: "deployments/scale"},
+				{Name: "statefulsets"}, {Name: "statefulsets/scale"},
+			},
+		},
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{{Name: "configmaps"}},
+		},
+	}
+	return kubeClient
+}
+
+func TestCalculateDesiredReplicasPredictionRequest(t *testing.T) {
+	var queries []url.Values
+	horizon := 0
+	predictor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query())
+		_ = json.NewEncoder(w).Encode(scaler.PredictionResponse{
+			// 窗口 600s、步长 60s 只需要 10 个点，多余的点应被丢弃
+			Values:  []float64{0.7, 0.7, 0.7, 0.7, 0.7, 0.7, 0.7, 0.7, 0.7, 0.7, 7.0},
+			Horizon: int32(horizon),
+		})
+	}))
+	defer predictor.Close()
+
+	manager := scaler.NewScalingManager(newTestKubeClient(), &fakescale.FakeScaleClient{}, newTestRESTMapper(),
+		&MockMetricsClient{}, predictor.URL)
+	hpa := createTestHPAModifier()
+	hpa.Namespace = "shop"
+	hpa.Spec.TargetRef.Name = "checkout"
+	hpa.Spec.PredictionWindow = 600
+
+	desiredReplicas, loadRatio, err := manager.CalculateDesiredReplicas(context.Background(), hpa, 0.5, 0.5)
+	assert.NoError(t, err)
+	assert.InDelta(t, 1.0, loadRatio, 1e-9)
+	assert.Equal(t, int32(1), desiredReplicas)
+
+	assert.Len(t, queries, 2)
+	assert.Equal(t, "cpu", queries[0].Get("target"))
+	assert.Equal(t, "memory", queries[1].Get("target"))
+	for _, q := range queries {
+		assert.Equal(t, "shop", q.Get("namespace"))
+		assert.Equal(t, "checkout", q.Get("workload"))
+		assert.Equal(t, "600", q.Get("horizon"))
+		assert.Equal(t, "60", q.Get("step"))
+	}
+
+	// 预测服务返回的窗口与请求不一致时拒绝该预测，退化为被动伸缩
+	horizon = 300
+	_, _, err = manager.CalculateDesiredReplicas(context.Background(), hpa, 0.5, 0.5)
+	assert.NoError(t, err)
+	condition := meta.FindStatusCondition(hpa.Status.Conditions, autoscalingv1.ConditionPredictorUnavailable)
+	assert.NotNil(t, condition)
+	assert.Contains(t, condition.Message, "does not match requested 600s")
+}
+
+func TestCalculateDesiredReplicasReactiveFallback(t *testing.T) {
+	predictorServer := newPredictorServer([]float64{1.75})
+	predictorURL := predictorServer.URL
+	// 关闭预测服务，模拟不可用
+	predictorServer.Close()
+
+	manager := scaler.NewScalingManager(newTestKubeClient(), &fakescale.FakeScaleClient{}, newTestRESTMapper(),
+		&MockMetricsClient{}, predictorURL)
+	hpa := createTestHPAModifier()
+	hpa.Status.CurrentReplicas = 2
+
+	// 实时 CPU 1.4 核 / 阈值 0.7 = 2 倍，副本数从 2 扩到 4
+	desiredReplicas, loadRatio, err := manager.CalculateDesiredReplicas(context.Background(), hpa, 1.4, 0.4)
+	assert.NoError(t, err)
+	assert.InDelta(t, 2.0, loadRatio, 1e-9)
+	assert.Equal(t, int32(4), desiredReplicas)
+	condition := meta.FindStatusCondition(hpa.Status.Conditions, autoscalingv1.ConditionPredictorUnavailable)
+	assert.Equal(t, metav1.ConditionTrue, condition.Status)
+	assert.Equal(t, predictor.ReasonUnreachable, condition.Reason)
+
+	// 预测服务恢复后切回预测模式：预测峰值 1.75 / 0.7 = 2.5 倍
+	predictorServer = newPredictorServer([]float64{1.75})
+	defer predictorServer.Close()
+	manager.PredictorURL = predictorServer.URL
+
+	desiredReplicas, _, err = manager.CalculateDesiredReplicas(context.Background(), hpa, 1.4, 0.4)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(5), desiredReplicas)
+	assert.True(t, meta.IsStatusConditionFalse(hpa.Status.Conditions, autoscalingv1.ConditionPredictorUnavailable))
+}
+
+func TestScaleWorkloadStatefulSet(t *testing.T) {
+	predictor := newPredictorServer([]float64{1.4})
+	defer predictor.Close()
+
+	scaleClient := &fakescale.FakeScaleClient{}
+	scaleClient.AddReactor("get", "statefulsets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &k8sautoscalingv1.Scale{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       k8sautoscalingv1.ScaleSpec{Replicas: 1},
+			Status:     k8sautoscalingv1.ScaleStatus{Replicas: 1, Selector: "app=web"},
+		}, nil
+	})
+	var updatedReplicas int32
+	scaleClient.AddReactor("update", "statefulsets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		obj := action.(k8stesting.UpdateAction).GetObject().(*k8sautoscalingv1.Scale)
+		updatedReplicas = obj.Spec.Replicas
+		return true, obj, nil
+	})
+
+	mockMetricsClient := &MockMetricsClient{}
+	podMetrics := createTestPodMetrics()
+	podMetrics.Items[0].Name = "web-0"
+	// 选择器来自 StatefulSet 的 /scale status
+	mockMetricsClient.On("GetPodMetrics", "default", "app=web").Return(podMetrics, nil)
+
+	manager := scaler.NewScalingManager(newTestKubeClient(), scaleClient, newTestRESTMapper(),
+		mockMetricsClient, predictor.URL)
+
+	hpa := createTestHPAModifier()
+	hpa.Spec.TargetRef = corev1.ObjectReference{
+		APIVersion: "apps/v1",
+		Kind:       "StatefulSet",
+		Name:       "web",
+		Namespace:  "default",
+	}
+
+	err := manager.ScaleWorkload(context.Background(), hpa)
+	assert.NoError(t, err)
+	// 预测负载 1.4 / CPU 阈值 0.7 = 2 倍，副本数从 1 扩到 2
+	assert.Equal(t, int32(2), updatedReplicas)
+	assert.Equal(t, int32(2), hpa.Status.CurrentReplicas)
+}
+
+func TestScaleWorkloadWithoutScaleSubresource(t *testing.T) {
+	predictor := newPredictorServer([]float64{0.7})
+	defer predictor.Close()
+
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(createTestPodMetrics(), nil)
+
+	manager := scaler.NewScalingManager(newTestKubeClient(), &fakescale.FakeScaleClient{}, newTestRESTMapper(),
+		mockMetricsClient, predictor.URL)
+
+	hpa := createTestHPAModifier()
+	hpa.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}}
+	hpa.Spec.TargetRef.APIVersion = "v1"
+	hpa.Spec.TargetRef.Kind = "ConfigMap"
+
+	err := manager.ScaleWorkload(context.Background(), hpa)
+	assert.Error(t, err)
+	assert.True(t, scaler.IsTargetError(err))
+	assert.Contains(t, err.Error(), "does not expose a /scale subresource")
+
+	hpa.Spec.TargetRef.APIVersion = "example.com/v1"
+	hpa.Spec.TargetRef.Kind = "Widget"
+	err = manager.ScaleWorkload(context.Background(), hpa)
+	assert.True(t, scaler.IsTargetError(err))
+}
+
+func TestScaleWorkloadStatusConditions(t *testing.T) {
+	// 预测负载 14 / CPU 阈值 0.7 = 20 倍，超过 MaxReplicas
+	predictorServer := newPredictorServer([]float64{14})
+	defer predictorServer.Close()
+
+	scaleClient := &fakescale.FakeScaleClient{}
+	scaleClient.AddReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &k8sautoscalingv1.Scale{
+			Spec:   k8sautoscalingv1.ScaleSpec{Replicas: 1},
+			Status: k8sautoscalingv1.ScaleStatus{Replicas: 1, Selector: "app=nginx"},
+		}, nil
+	})
+	scaleClient.AddReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, action.(k8stesting.UpdateAction).GetObject(), nil
+	})
+
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(createTestPodMetrics(), nil)
+
+	manager := scaler.NewScalingManager(newTestKubeClient(), scaleClient, newTestRESTMapper(),
+		mockMetricsClient, predictorServer.URL)
+
+	hpa := createTestHPAModifier()
+	hpa.Generation = 3
+	err := manager.ScaleWorkload(context.Background(), hpa)
+	assert.NoError(t, err)
+
+	assert.Equal(t, int32(10), hpa.Status.DesiredReplicas)
+	assert.Equal(t, "Stable", hpa.Status.WorkloadPattern)
+	assert.Equal(t, "Stable", hpa.Status.Strategy)
+
+	expected := map[string]struct {
+		status metav1.ConditionStatus
+		reason string
+	}{
+		autoscalingv1.ConditionReady:               {metav1.ConditionTrue, autoscalingv1.ReasonReconciled},
+		autoscalingv1.ConditionAbleToScale:         {metav1.ConditionTrue, autoscalingv1.ReasonSucceededRescale},
+		autoscalingv1.ConditionScalingLimited:      {metav1.ConditionTrue, autoscalingv1.ReasonTooManyReplicas},
+		autoscalingv1.ConditionMetricsAvailable:    {metav1.ConditionTrue, autoscalingv1.ReasonMetricsCollected},
+		autoscalingv1.ConditionPredictionAvailable: {metav1.ConditionTrue, autoscalingv1.ReasonPredictionSucceeded},
+	}
+	for conditionType, want := range expected {
+		condition := meta.FindStatusCondition(hpa.Status.Conditions, conditionType)
+		if assert.NotNil(t, condition, conditionType) {
+			assert.Equal(t, want.status, condition.Status, conditionType)
+			assert.Equal(t, want.reason, condition.Reason, conditionType)
+			assert.Equal(t, int64(3), condition.ObservedGeneration, conditionType)
+		}
+	}
+
+	// 目标无法伸缩时 Ready 为 False，AbleToScale 记录具体原因
+	hpa.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}}
+	hpa.Spec.TargetRef.APIVersion = "v1"
+	hpa.Spec.TargetRef.Kind = "ConfigMap"
+	err = manager.ScaleWorkload(context.Background(), hpa)
+	assert.Error(t, err)
+	assert.True(t, meta.IsStatusConditionFalse(hpa.Status.Conditions, autoscalingv1.ConditionReady))
+	condition := meta.FindStatusCondition(hpa.Status.Conditions, autoscalingv1.ConditionAbleToScale)
+	assert.Equal(t, scaler.ReasonNoScaleSubresource, condition.Reason)
+}
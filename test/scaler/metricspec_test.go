@@ -0,0 +1,144 @@
+package scaler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakescale "k8s.io/client-go/scale/fake"
+	k8stesting "k8s.io/client-go/testing"
+	custommetricsv1beta2 "k8s.io/metrics/pkg/apis/custom_metrics/v1beta2"
+	externalmetricsv1beta1 "k8s.io/metrics/pkg/apis/external_metrics/v1beta1"
+	fakecustom "k8s.io/metrics/pkg/client/custom_metrics/fake"
+	fakeexternal "k8s.io/metrics/pkg/client/external_metrics/fake"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+	"yemo.info/auto-scaling-system/internal/scaler"
+)
+
+// newMetricSpecHPAModifier 按 CPU、每个 Pod 的请求速率和外部队列长度伸缩的 HPAModifier
+func newMetricSpecHPAModifier() *autoscalingv1.HPAModifier {
+	hpa := createTestHPAModifier()
+	hpa.Spec.MaxReplicas = 20
+	hpa.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}}
+	hpa.Spec.Metrics = []autoscalingv2.MetricSpec{
+		{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name:   corev1.ResourceCPU,
+				Target: autoscalingv2.MetricTarget{Type: autoscalingv2.AverageValueMetricType, AverageValue: resource.NewMilliQuantity(500, resource.DecimalSI)},
+			},
+		},
+		{
+			Type: autoscalingv2.PodsMetricSourceType,
+			Pods: &autoscalingv2.PodsMetricSource{
+				Metric: autoscalingv2.MetricIdentifier{Name: "http_requests_per_second"},
+				Target: autoscalingv2.MetricTarget{Type: autoscalingv2.AverageValueMetricType, AverageValue: resource.NewQuantity(100, resource.DecimalSI)},
+			},
+		},
+		{
+			Type: autoscalingv2.ExternalMetricSourceType,
+			External: &autoscalingv2.ExternalMetricSource{
+				Metric: autoscalingv2.MetricIdentifier{Name: "queue_depth"},
+				Target: autoscalingv2.MetricTarget{Type: autoscalingv2.AverageValueMetricType, AverageValue: resource.NewQuantity(50, resource.DecimalSI)},
+			},
+		},
+	}
+	hpa.Status.CurrentReplicas = 2
+	return hpa
+}
+
+// newFakeCustomMetrics 每个 Pod 返回给定的指标值
+func newFakeCustomMetrics(values ...int64) *fakecustom.FakeCustomMetricsClient {
+	client := &fakecustom.FakeCustomMetricsClient{}
+	client.AddReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		list := &custommetricsv1beta2.MetricValueList{}
+		for _, value := range values {
+			list.Items = append(list.Items, custommetricsv1beta2.MetricValue{Value: *resource.NewQuantity(value, resource.DecimalSI)})
+		}
+		return true, list, nil
+	})
+	return client
+}
+
+// newFakeExternalMetrics 为外部指标返回给定的值
+func newFakeExternalMetrics(values ...int64) *fakeexternal.FakeExternalMetricsClient {
+	client := &fakeexternal.FakeExternalMetricsClient{}
+	client.AddReactor("list", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		list := &externalmetricsv1beta1.ExternalMetricValueList{}
+		for _, value := range values {
+			list.Items = append(list.Items, externalmetricsv1beta1.ExternalMetricValue{Value: *resource.NewQuantity(value, resource.DecimalSI)})
+		}
+		return true, list, nil
+	})
+	return client
+}
+
+func TestCalculateDesiredReplicasMetricSpec(t *testing.T) {
+	// 按指标返回不同的预测峰值
+	var targets []string
+	peaks := map[string]float64{"cpu": 0.2, "memory": 0.5, "pods/http_requests_per_second": 300, "external/queue_depth": 400}
+	predictorServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := r.URL.Query().Get("target")
+		targets = append(targets, target)
+		_ = json.NewEncoder(w).Encode(scaler.PredictionResponse{Values: []float64{peaks[target]}})
+	}))
+	defer predictorServer.Close()
+
+	manager := scaler.NewScalingManager(newTestKubeClient(), &fakescale.FakeScaleClient{}, newTestRESTMapper(),
+		&MockMetricsClient{}, predictorServer.URL)
+	manager.CustomMetrics = newFakeCustomMetrics(150, 250)
+	manager.ExternalMetrics = newFakeExternalMetrics(100, 200)
+	hpa := newMetricSpecHPAModifier()
+
+	// CPU：0.2 / 0.5 * 2 = 1；请求速率：300 / 100 * 2 = 6；队列长度：400 / 50 = 8，取最大的 8
+	desiredReplicas, loadRatio, err := manager.CalculateDesiredReplicas(context.Background(), hpa, 0.1, 0.3)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(8), desiredReplicas)
+	assert.InDelta(t, 4.0, loadRatio, 1e-9)
+	assert.Equal(t, []string{"cpu", "pods/http_requests_per_second", "external/queue_depth"}, targets)
+	assert.True(t, meta.IsStatusConditionTrue(hpa.Status.Conditions, autoscalingv1.ConditionPredictionAvailable))
+}
+
+func TestCalculateDesiredReplicasMetricSpecFailures(t *testing.T) {
+	predictorServer := newPredictorServer([]float64{0})
+	predictorURL := predictorServer.URL
+	// 关闭预测服务，按当前值计算
+	predictorServer.Close()
+
+	manager := scaler.NewScalingManager(newTestKubeClient(), &fakescale.FakeScaleClient{}, newTestRESTMapper(),
+		&MockMetricsClient{}, predictorURL)
+	manager.CustomMetrics = newFakeCustomMetrics(150, 250)
+	hpa := newMetricSpecHPAModifier()
+	hpa.Status.CurrentReplicas = 10
+
+	// 外部指标不可用时按其余指标计算：请求速率 200 / 100 * 10 = 20
+	desiredReplicas, _, err := manager.CalculateDesiredReplicas(context.Background(), hpa, 0.2, 0.3)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(20), desiredReplicas)
+	condition := meta.FindStatusCondition(hpa.Status.Conditions, autoscalingv1.ConditionMetricsAvailable)
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+	assert.Contains(t, condition.Message, "external/queue_depth")
+	assert.True(t, meta.IsStatusConditionTrue(hpa.Status.Conditions, autoscalingv1.ConditionPredictorUnavailable))
+
+	// 部分指标缺失时不缩容：CPU 0.2 / 0.5 * 10 = 4，保持 10
+	hpa.Spec.Metrics = []autoscalingv2.MetricSpec{hpa.Spec.Metrics[0], hpa.Spec.Metrics[2]}
+	desiredReplicas, _, err = manager.CalculateDesiredReplicas(context.Background(), hpa, 0.2, 0.3)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(10), desiredReplicas)
+
+	// 所有指标都不可用时报错
+	hpa.Spec.Metrics = hpa.Spec.Metrics[1:]
+	_, _, err = manager.CalculateDesiredReplicas(context.Background(), hpa, 0.2, 0.3)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "external metrics API is not configured")
+}
@@ -0,0 +1,166 @@
+package scaler_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8sautoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakescale "k8s.io/client-go/scale/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+	"yemo.info/auto-scaling-system/internal/scaler"
+)
+
+func newNativeHPA() *autoscalingv2.HorizontalPodAutoscaler {
+	minReplicas := int32(1)
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       "nginx-deployment",
+			},
+			MinReplicas: &minReplicas,
+			MaxReplicas: 5,
+		},
+		Status: autoscalingv2.HorizontalPodAutoscalerStatus{CurrentReplicas: 2},
+	}
+}
+
+func TestScaleWorkloadWithNativeHPA(t *testing.T) {
+	// 预测负载 1.75 / CPU 阈值 0.7，期望 3 个副本
+	predictorServer := newPredictorServer([]float64{1.75})
+	defer predictorServer.Close()
+
+	scaleClient := &fakescale.FakeScaleClient{}
+	var scaleUpdates int
+	scaleClient.AddReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		scaleUpdates++
+		return true, action.(k8stesting.UpdateAction).GetObject(), nil
+	})
+	scaleClient.AddReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &k8sautoscalingv1.Scale{Spec: k8sautoscalingv1.ScaleSpec{Replicas: 1}}, nil
+	})
+
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(createTestPodMetrics(), nil)
+
+	kubeClient := newTestKubeClient(newNativeHPA())
+	manager := scaler.NewScalingManager(kubeClient, scaleClient, newTestRESTMapper(), mockMetricsClient, predictorServer.URL)
+
+	hpa := createTestHPAModifier()
+	hpa.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}}
+	hpa.Spec.MaxReplicas = 8
+
+	// 默认拒绝伸缩并记录冲突
+	err := manager.ScaleWorkload(context.Background(), hpa)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, scaleUpdates)
+	assert.True(t, meta.IsStatusConditionTrue(hpa.Status.Conditions, autoscalingv1.ConditionNativeHPAConflict))
+	assert.True(t, meta.IsStatusConditionFalse(hpa.Status.Conditions, autoscalingv1.ConditionAbleToScale))
+
+	// Modify 模式下调整 HPA 的上下限
+	hpa.Spec.NativeHPA = autoscalingv1.NativeHPAModify
+	err = manager.ScaleWorkload(context.Background(), hpa)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, scaleUpdates)
+	assert.True(t, meta.IsStatusConditionFalse(hpa.Status.Conditions, autoscalingv1.ConditionNativeHPAConflict))
+	// currentReplicas 仍来自 /scale 子资源
+	assert.Equal(t, int32(1), hpa.Status.CurrentReplicas)
+
+	// 只修改 minReplicas，保留用户设置的 maxReplicas
+	hpas := kubeClient.AutoscalingV2().HorizontalPodAutoscalers("default")
+	updated, err := hpas.Get(context.Background(), "nginx", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), *updated.Spec.MinReplicas)
+	assert.Equal(t, int32(5), updated.Spec.MaxReplicas)
+
+	// minReplicas 不超过 HPA 自身的 maxReplicas
+	predictorServer.Close()
+	peakServer := newPredictorServer([]float64{7})
+	defer peakServer.Close()
+	manager.PredictorURL = peakServer.URL
+	assert.NoError(t, manager.ScaleWorkload(context.Background(), hpa))
+	updated, err = hpas.Get(context.Background(), "nginx", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(5), *updated.Spec.MinReplicas)
+	assert.Equal(t, int32(5), updated.Spec.MaxReplicas)
+
+	// HPAModifier 删除后恢复原来的 minReplicas
+	assert.NoError(t, manager.Forget(context.Background(), "default", hpa.Name))
+	updated, err = hpas.Get(context.Background(), "nginx", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), *updated.Spec.MinReplicas)
+	assert.Empty(t, updated.Annotations)
+
+	// 其他工作负载不受影响
+	hpa.Spec.TargetRef.Name = "other"
+	hpa.Status.LastScaledTime = nil
+	err = manager.ScaleWorkload(context.Background(), hpa)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, scaleUpdates)
+	assert.True(t, meta.IsStatusConditionFalse(hpa.Status.Conditions, autoscalingv1.ConditionNativeHPAConflict))
+}
+
+func TestScaleWorkloadKeepsNativeHPAMinReplicas(t *testing.T) {
+	// 期望 3 个副本，低于 HPA 自己的 minReplicas 5
+	predictorServer := newPredictorServer([]float64{1.75})
+	defer predictorServer.Close()
+
+	scaleClient := &fakescale.FakeScaleClient{}
+	scaleClient.AddReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &k8sautoscalingv1.Scale{Spec: k8sautoscalingv1.ScaleSpec{Replicas: 1}}, nil
+	})
+
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(createTestPodMetrics(), nil)
+
+	nativeHPA := newNativeHPA()
+	minReplicas := int32(5)
+	nativeHPA.Spec.MinReplicas = &minReplicas
+	nativeHPA.Spec.MaxReplicas = 10
+	kubeClient := newTestKubeClient(nativeHPA)
+	manager := scaler.NewScalingManager(kubeClient, scaleClient, newTestRESTMapper(), mockMetricsClient, predictorServer.URL)
+
+	hpa := createTestHPAModifier()
+	hpa.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}}
+	hpa.Spec.MaxReplicas = 10
+	hpa.Spec.NativeHPA = autoscalingv1.NativeHPAModify
+
+	// 不降低 HPA 原来的 minReplicas，也不留下注解
+	assert.NoError(t, manager.ScaleWorkload(context.Background(), hpa))
+	assert.True(t, meta.IsStatusConditionFalse(hpa.Status.Conditions, autoscalingv1.ConditionNativeHPAConflict))
+	hpas := kubeClient.AutoscalingV2().HorizontalPodAutoscalers("default")
+	updated, err := hpas.Get(context.Background(), "nginx", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(5), *updated.Spec.MinReplicas)
+	assert.Empty(t, updated.Annotations)
+
+	// 期望副本数超过原来的下限时提高 minReplicas
+	predictorServer.Close()
+	peakServer := newPredictorServer([]float64{4.55})
+	defer peakServer.Close()
+	manager.PredictorURL = peakServer.URL
+	assert.NoError(t, manager.ScaleWorkload(context.Background(), hpa))
+	updated, err = hpas.Get(context.Background(), "nginx", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(7), *updated.Spec.MinReplicas)
+
+	// 回落到原来的下限以内时恢复 HPA
+	peakServer.Close()
+	lowServer := newPredictorServer([]float64{1.75})
+	defer lowServer.Close()
+	manager.PredictorURL = lowServer.URL
+	assert.NoError(t, manager.ScaleWorkload(context.Background(), hpa))
+	updated, err = hpas.Get(context.Background(), "nginx", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(5), *updated.Spec.MinReplicas)
+	assert.Empty(t, updated.Annotations)
+}
@@ -0,0 +1,190 @@
+package scaler_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	k8sautoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakescale "k8s.io/client-go/scale/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+	"yemo.info/auto-scaling-system/internal/scaler"
+)
+
+func TestHistorySnapshotRoundTrip(t *testing.T) {
+	store := scaler.NewHistoryStore(time.Hour, time.Minute)
+	start := time.Unix(1735689600, 0)
+	store.Add("default/web", start, 1)
+	store.Add("default/web", start.Add(2*time.Minute), 3)
+	store.Add("default/web#memory", start, 0.5)
+	store.Add("default/api", start, 9)
+
+	snapshot := store.Snapshot("default/web")
+	assert.Equal(t, "default/web", snapshot.Workload)
+	assert.Len(t, snapshot.Series, 2)
+	assert.Equal(t, []float64{1, 2, 3}, snapshot.Series["default/web"].Values)
+	assert.Nil(t, store.Snapshot("default/missing"))
+
+	restored := scaler.NewHistoryStore(time.Hour, time.Minute)
+	restored.Restore(snapshot)
+	assert.Equal(t, store.Samples("default/web"), restored.Samples("default/web"))
+	assert.Equal(t, []float64{0.5}, restored.Values("default/web#memory"))
+	assert.Equal(t, []string{"default/web"}, restored.Workloads())
+}
+
+func TestHistoryPersisters(t *testing.T) {
+	persisters := map[string]scaler.HistoryPersister{
+		"configmap": &scaler.ConfigMapPersister{Client: newTestKubeClient(), Namespace: "auto-scaling-system"},
+		"file":      &scaler.FilePersister{Dir: t.TempDir()},
+	}
+	snapshot := &scaler.HistorySnapshot{
+		Workload: "default/web",
+		Interval: 60,
+		Series:   map[string]scaler.SeriesSnapshot{"default/web": {Start: 1735689600, Values: []float64{1, 2}}},
+	}
+
+	for name, persister := range persisters {
+		ctx := context.Background()
+		assert.NoError(t, persister.Save(ctx, snapshot), name)
+		// 再次保存覆盖旧快照
+		snapshot.Series["default/web"] = scaler.SeriesSnapshot{Start: 1735689600, Values: []float64{1, 2, 3}}
+		assert.NoError(t, persister.Save(ctx, snapshot), name)
+
+		loaded, err := persister.Load(ctx)
+		assert.NoError(t, err, name)
+		assert.Equal(t, []*scaler.HistorySnapshot{snapshot}, loaded, name)
+
+		assert.NoError(t, persister.Delete(ctx, "default/web"), name)
+		assert.NoError(t, persister.Delete(ctx, "default/web"), name)
+		loaded, err = persister.Load(ctx)
+		assert.NoError(t, err, name)
+		assert.Empty(t, loaded, name)
+		snapshot.Series["default/web"] = scaler.SeriesSnapshot{Start: 1735689600, Values: []float64{1, 2}}
+	}
+}
+
+func TestHistoryPersistersSkipCorruptSnapshots(t *testing.T) {
+	kubeClient := newTestKubeClient()
+	dir := t.TempDir()
+	persisters := map[string]scaler.HistoryPersister{
+		"configmap": &scaler.ConfigMapPersister{Client: kubeClient, Namespace: "auto-scaling-system"},
+		"file":      &scaler.FilePersister{Dir: dir},
+	}
+	// 写入一半时中断留下的快照
+	_, err := kubeClient.CoreV1().ConfigMaps("auto-scaling-system").Create(context.Background(), &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "hpamodifier-history-corrupt",
+			Namespace: "auto-scaling-system",
+			Labels:    map[string]string{"autoscaling.yemo.info/history": "true"},
+		},
+		Data: map[string]string{"snapshot.json": `{"workload":"default/broken","series":`},
+	}, metav1.CreateOptions{})
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "hpamodifier-history-corrupt.json"), []byte(`{"workload":`), 0o644))
+
+	for name, persister := range persisters {
+		ctx := context.Background()
+		var want []*scaler.HistorySnapshot
+		for _, workload := range []string{"default/api", "default/web"} {
+			snapshot := &scaler.HistorySnapshot{
+				Workload: workload,
+				Interval: 60,
+				Series:   map[string]scaler.SeriesSnapshot{workload: {Start: 1735689600, Values: []float64{1, 2}}},
+			}
+			assert.NoError(t, persister.Save(ctx, snapshot), name)
+			want = append(want, snapshot)
+		}
+
+		// 损坏的快照被跳过，其余快照照常恢复
+		loaded, err := persister.Load(ctx)
+		assert.NoError(t, err, name)
+		assert.ElementsMatch(t, want, loaded, name)
+	}
+}
+
+func TestScalingManagerHistoryFailover(t *testing.T) {
+	predictorServer := newPredictorServer([]float64{0.7})
+	defer predictorServer.Close()
+
+	scaleClient := &fakescale.FakeScaleClient{}
+	scaleClient.AddReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &k8sautoscalingv1.Scale{
+			Spec:   k8sautoscalingv1.ScaleSpec{Replicas: 1},
+			Status: k8sautoscalingv1.ScaleStatus{Replicas: 1, Selector: "app=nginx"},
+		}, nil
+	})
+	scaleClient.AddReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, action.(k8stesting.UpdateAction).GetObject(), nil
+	})
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(createTestPodMetrics(), nil)
+
+	persister := &scaler.FilePersister{Dir: t.TempDir()}
+	ctx := context.Background()
+
+	// 旧 leader 学习到历史数据并保存
+	leader := scaler.NewScalingManager(newTestKubeClient(), scaleClient, newTestRESTMapper(), mockMetricsClient, predictorServer.URL)
+	leader.HistoryPersister = persister
+	hpa := createTestHPAModifier()
+	assert.NoError(t, leader.ScaleWorkload(ctx, hpa))
+	assert.NoError(t, leader.SaveHistory(ctx))
+	saved, err := persister.Load(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, saved, 1)
+	assert.Len(t, saved[0].Series, 2, "cpu 和 memory 两条序列")
+
+	// 新 leader 启动时恢复
+	successor := scaler.NewScalingManager(newTestKubeClient(), scaleClient, newTestRESTMapper(), mockMetricsClient, predictorServer.URL)
+	successor.HistoryPersister = persister
+	assert.NoError(t, successor.LoadHistory(ctx))
+	assert.NoError(t, successor.SaveHistory(ctx))
+	reloaded, err := persister.Load(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, saved, reloaded)
+
+	// HPAModifier 删除后快照也被删除
+	assert.NoError(t, successor.ScaleWorkload(ctx, hpa))
+	assert.NoError(t, successor.Forget(ctx, hpa.Namespace, hpa.Name))
+	remaining, err := persister.Load(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, remaining)
+}
+
+func TestHistorySyncerPrunesOrphanedSnapshots(t *testing.T) {
+	persister := &scaler.FilePersister{Dir: t.TempDir()}
+	ctx, cancel := context.WithCancel(context.Background())
+	start := time.Now().Add(-time.Hour).Unix()
+	for _, workload := range []string{"default/nginx-deployment", "default/deleted"} {
+		assert.NoError(t, persister.Save(ctx, &scaler.HistorySnapshot{
+			Workload: workload,
+			Interval: 300,
+			Series:   map[string]scaler.SeriesSnapshot{workload: {Start: start, Values: []float64{1, 2}}},
+		}))
+	}
+
+	scheme := runtime.NewScheme()
+	_ = autoscalingv1.AddToScheme(scheme)
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(createTestHPAModifier()).Build()
+	manager := scaler.NewScalingManager(newTestKubeClient(), &fakescale.FakeScaleClient{}, newTestRESTMapper(), &MockMetricsClient{}, "")
+	manager.HistoryPersister = persister
+
+	// 停止期间删除的 HPAModifier 留下的快照在恢复后被清理，退出时不再保存
+	cancel()
+	syncer := &scaler.HistorySyncer{Manager: manager, Reader: reader, Interval: time.Hour, Log: logr.Discard()}
+	assert.NoError(t, syncer.Start(ctx))
+	remaining, err := persister.Load(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, remaining, 1) {
+		assert.Equal(t, "default/nginx-deployment", remaining[0].Workload)
+	}
+}
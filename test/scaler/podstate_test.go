@@ -0,0 +1,116 @@
+package scaler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakescale "k8s.io/client-go/scale/fake"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+	"yemo.info/auto-scaling-system/internal/scaler"
+)
+
+func TestCollectMetricsExcludesPodsByState(t *testing.T) {
+	ready := newReadyPod("ready")
+	unready := newReadyPod("unready")
+	unready.Status.Conditions[0].Status = corev1.ConditionFalse
+	// 启动 1 分钟、30 秒前才就绪的 Pod，1 分钟的样本窗口包含就绪前的用量
+	initializing := newReadyPod("initializing")
+	initializing.Status.StartTime = &metav1.Time{Time: time.Now().Add(-time.Minute)}
+	initializing.Status.Conditions[0].LastTransitionTime = metav1.NewTime(time.Now().Add(-30 * time.Second))
+	// 同样刚启动，但就绪后已有完整的样本窗口
+	settled := newReadyPod("settled")
+	settled.Status.StartTime = &metav1.Time{Time: time.Now().Add(-2 * time.Minute)}
+	settled.Status.Conditions[0].LastTransitionTime = metav1.NewTime(time.Now().Add(-90 * time.Second))
+	terminating := newReadyPod("terminating")
+	terminating.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	terminating.Finalizers = []string{"example.com/hold"}
+	failed := newReadyPod("failed")
+	failed.Status.Phase = corev1.PodFailed
+
+	// 就绪的 Pod 和找不到 Pod 对象的指标各用 500m，其余 Pod 处于预热或异常状态，用 2 核
+	podMetrics := &metricsv1beta1.PodMetricsList{}
+	for name, cpu := range map[string]string{
+		"ready": "500m", "unknown": "500m", "settled": "500m", "unready": "2", "initializing": "2", "terminating": "2", "failed": "2",
+	} {
+		podMetrics.Items = append(podMetrics.Items, metricsv1beta1.PodMetrics{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Timestamp:  metav1.NewTime(time.Now()),
+			Window:     metav1.Duration{Duration: time.Minute},
+			Containers: []metricsv1beta1.ContainerMetrics{newContainerMetrics("app", cpu, "1Gi")},
+		})
+	}
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(podMetrics, nil)
+
+	kubeClient := newTestKubeClient(ready, settled, unready, initializing, terminating, failed)
+	manager := scaler.NewScalingManager(kubeClient, &fakescale.FakeScaleClient{}, newTestRESTMapper(), mockMetricsClient, "")
+	hpa := createTestHPAModifier()
+	hpa.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}}
+
+	cpuUsage, _, err := manager.CollectMetrics(context.Background(), hpa)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.5, cpuUsage, 1e-9)
+	assert.Equal(t, int32(3), hpa.Status.MetricPods)
+	assert.Equal(t, autoscalingv1.PodExclusions{Unready: 1, Initializing: 1, Terminating: 1, Finished: 1}, hpa.Status.ExcludedPods)
+	// 仍在初始化的 Pod 不算作已就绪
+	assert.Equal(t, int32(2), hpa.Status.ReadyReplicas)
+
+	// 初始化时间为 0 时刚启动的 Pod 也计入
+	hpa.Spec.PodInitializationPeriod = new(int32)
+	cpuUsage, _, err = manager.CollectMetrics(context.Background(), hpa)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.875, cpuUsage, 1e-9)
+	assert.Equal(t, int32(4), hpa.Status.MetricPods)
+	assert.Equal(t, int32(0), hpa.Status.ExcludedPods.Initializing)
+}
+
+func TestCollectMetricsAllPodsExcluded(t *testing.T) {
+	// 滚动更新刚完成，所有 Pod 都在样本窗口内才就绪
+	var pods []runtime.Object
+	podMetrics := &metricsv1beta1.PodMetricsList{}
+	for _, name := range []string{"new-a", "new-b"} {
+		pod := newReadyPod(name)
+		pod.Status.StartTime = &metav1.Time{Time: time.Now().Add(-20 * time.Second)}
+		pod.Status.Conditions[0].LastTransitionTime = metav1.NewTime(time.Now().Add(-10 * time.Second))
+		pods = append(pods, pod)
+		podMetrics.Items = append(podMetrics.Items, metricsv1beta1.PodMetrics{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Timestamp:  metav1.NewTime(time.Now()),
+			Window:     metav1.Duration{Duration: time.Minute},
+			Containers: []metricsv1beta1.ContainerMetrics{newContainerMetrics("app", "2", "1Gi")},
+		})
+	}
+	unready := newReadyPod("unready")
+	unready.Status.Conditions[0].Status = corev1.ConditionFalse
+	pods = append(pods, unready)
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(podMetrics, nil).Once()
+
+	manager := scaler.NewScalingManager(newTestKubeClient(pods...), &fakescale.FakeScaleClient{}, newTestRESTMapper(), mockMetricsClient, "")
+	hpa := createTestHPAModifier()
+	hpa.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}}
+
+	// 只剩初始化中的 Pod 时使用它们的样本，不停止伸缩；它们不算作已就绪，按当前副本数计算
+	cpuUsage, _, err := manager.CollectMetrics(context.Background(), hpa)
+	assert.NoError(t, err)
+	assert.InDelta(t, 2.0, cpuUsage, 1e-9)
+	assert.Equal(t, int32(2), hpa.Status.MetricPods)
+	assert.Equal(t, int32(0), hpa.Status.ReadyReplicas)
+
+	// 所有 Pod 都未就绪时无法计算
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(&metricsv1beta1.PodMetricsList{Items: []metricsv1beta1.PodMetrics{{
+		ObjectMeta: metav1.ObjectMeta{Name: "unready", Namespace: "default"},
+		Containers: []metricsv1beta1.ContainerMetrics{newContainerMetrics("app", "2", "1Gi")},
+	}}}, nil)
+	_, _, err = manager.CollectMetrics(context.Background(), hpa)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "1 unready")
+	assert.Equal(t, int32(0), hpa.Status.MetricPods)
+}
@@ -0,0 +1,60 @@
+package scaler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"yemo.info/auto-scaling-system/internal/scaler"
+)
+
+// preWarmForecast 返回 points 个 0.5 的预测值，第 peak 个为 1.5
+func preWarmForecast(points, peak int) []float64 {
+	values := make([]float64, points)
+	for i := range values {
+		values[i] = 0.5
+	}
+	values[peak] = 1.5
+	return values
+}
+
+func TestScaleWorkloadPlansPreWarm(t *testing.T) {
+	// 预测窗口 5 分钟内负载平稳，第 10 分钟出现 3 倍于阈值的峰值
+	predictorServer := newPredictorServer(preWarmForecast(16, 9))
+	defer predictorServer.Close()
+
+	replicas := int32(2)
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(createTestPodMetrics(), nil)
+	manager := scaler.NewScalingManager(newTestKubeClient(), newLiveScaleClient(&replicas), newTestRESTMapper(),
+		mockMetricsClient, predictorServer.URL)
+	manager.HistorySource = &periodicHistorySource{}
+
+	hpa := createTestHPAModifier()
+	hpa.Spec.CPUThreshold = 0.5
+	now := time.Now()
+	assert.NoError(t, manager.ScaleWorkload(context.Background(), hpa))
+	assert.Equal(t, "Periodic", hpa.Status.Strategy)
+
+	// 峰值前 Pod 启动耗时处计划扩到 6 个副本，当前不扩容
+	assert.Equal(t, int32(2), replicas)
+	if assert.NotNil(t, hpa.Status.NextPreWarm) {
+		assert.Equal(t, int32(6), hpa.Status.NextPreWarm.Replicas)
+		assert.WithinDuration(t, now.Add(10*time.Minute), hpa.Status.NextPreWarm.PeakTime.Time, 5*time.Second)
+		assert.WithinDuration(t, now.Add(10*time.Minute-scaler.DefaultPodStartupLatency),
+			hpa.Status.NextPreWarm.ScaleUpTime.Time, 5*time.Second)
+	}
+
+	// 峰值在 Pod 启动耗时之内时立即扩容
+	imminentServer := newPredictorServer(preWarmForecast(32, 1))
+	defer imminentServer.Close()
+	manager.PredictorURL = imminentServer.URL
+	manager.PredictionStep = 30 * time.Second
+	hpa.Spec.PredictionWindow = 30
+	hpa.Status.LastScaledTime = nil
+	assert.NoError(t, manager.ScaleWorkload(context.Background(), hpa))
+	assert.Equal(t, int32(6), replicas)
+	assert.Nil(t, hpa.Status.NextPreWarm)
+}
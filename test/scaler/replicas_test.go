@@ -0,0 +1,188 @@
+package scaler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	k8sautoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakescale "k8s.io/client-go/scale/fake"
+	k8stesting "k8s.io/client-go/testing"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+
+	"yemo.info/auto-scaling-system/internal/scaler"
+)
+
+// newLiveScaleClient 返回 spec.replicas 为 *replicas 的 /scale 子资源，更新时写回 *replicas
+func newLiveScaleClient(replicas *int32) *fakescale.FakeScaleClient {
+	scaleClient := &fakescale.FakeScaleClient{}
+	scaleClient.AddReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &k8sautoscalingv1.Scale{
+			Spec:   k8sautoscalingv1.ScaleSpec{Replicas: *replicas},
+			Status: k8sautoscalingv1.ScaleStatus{Replicas: *replicas, Selector: "app=nginx"},
+		}, nil
+	})
+	scaleClient.AddReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		obj := action.(k8stesting.UpdateAction).GetObject().(*k8sautoscalingv1.Scale)
+		*replicas = obj.Spec.Replicas
+		return true, obj, nil
+	})
+	return scaleClient
+}
+
+// newNamedPodMetrics 每个 Pod 用 500m CPU 和 1GiB 内存
+func newNamedPodMetrics(names ...string) *metricsv1beta1.PodMetricsList {
+	list := &metricsv1beta1.PodMetricsList{}
+	for _, name := range names {
+		list.Items = append(list.Items, metricsv1beta1.PodMetrics{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Containers: []metricsv1beta1.ContainerMetrics{newContainerMetrics("app", "500m", "1Gi")},
+		})
+	}
+	return list
+}
+
+func TestScaleWorkloadUsesLiveReplicas(t *testing.T) {
+	// 预测负载 0.75 / CPU 阈值 0.5 = 1.5 倍
+	predictorServer := newPredictorServer([]float64{0.75})
+	defer predictorServer.Close()
+
+	replicas := int32(4)
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(newNamedPodMetrics("pod-a", "pod-b"), nil)
+	manager := scaler.NewScalingManager(newTestKubeClient(), newLiveScaleClient(&replicas), newTestRESTMapper(),
+		mockMetricsClient, predictorServer.URL)
+
+	// 新建的 HPAModifier 没有 status，按目标实际的 4 个副本计算
+	hpa := createTestHPAModifier()
+	hpa.Spec.CPUThreshold = 0.5
+	hpa.Status.CurrentReplicas = 0
+	assert.NoError(t, manager.ScaleWorkload(context.Background(), hpa))
+	assert.Equal(t, int32(6), replicas)
+	assert.Equal(t, int32(6), hpa.Status.CurrentReplicas)
+	assert.Equal(t, int32(6), hpa.Status.LastAppliedReplicas)
+	assert.Equal(t, int32(0), hpa.Status.ReplicaDrift)
+
+	// 手动扩到 8 个副本后以 8 为基数，并记录偏差
+	replicas = 8
+	hpa.Status.LastScaledTime = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+	assert.NoError(t, manager.ScaleWorkload(context.Background(), hpa))
+	assert.Equal(t, int32(10), replicas)
+	assert.Equal(t, int32(2), hpa.Status.ReplicaDrift)
+}
+
+func TestCalculateDesiredReplicasWithStartingPods(t *testing.T) {
+	// 预测服务不可用，按实时用量计算
+	predictorServer := newPredictorServer([]float64{0})
+	predictorServer.Close()
+	manager := scaler.NewScalingManager(newTestKubeClient(), &fakescale.FakeScaleClient{}, newTestRESTMapper(),
+		&MockMetricsClient{}, predictorServer.URL)
+
+	// 4 个副本中只有 2 个就绪，另外 2 个仍在启动
+	hpa := createTestHPAModifier()
+	hpa.Spec.CPUThreshold = 0.5
+	hpa.Status.CurrentReplicas = 4
+	hpa.Status.ReadyReplicas = 2
+	hpa.Status.StartingReplicas = 2
+
+	// 就绪 Pod 的负载为阈值的 1.5 倍，启动中的 2 个 Pod 足以分担，不缩容
+	desiredReplicas, _, err := manager.CalculateDesiredReplicas(context.Background(), hpa, 0.75, 0.4)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(4), desiredReplicas)
+
+	// 负载为阈值的 3 倍时按就绪的 2 个 Pod 扩到 6，而不是 12
+	desiredReplicas, _, err = manager.CalculateDesiredReplicas(context.Background(), hpa, 1.5, 0.4)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(6), desiredReplicas)
+
+	// 负载较低时也不缩容
+	desiredReplicas, _, err = manager.CalculateDesiredReplicas(context.Background(), hpa, 0.1, 0.1)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(4), desiredReplicas)
+}
+
+func TestScaleWorkloadScalesDownPastStuckPod(t *testing.T) {
+	predictorServer := newPredictorServer([]float64{0})
+	predictorServer.Close()
+
+	replicas := int32(4)
+	scaleClient := newLiveScaleClient(&replicas)
+	// 启动一小时后仍未就绪的 Pod 不再算作启动中
+	stuck := newReadyPod("pod-d")
+	stuck.Status.Conditions[0].Status = corev1.ConditionFalse
+	kubeClient := newTestKubeClient(newReadyPod("pod-a"), newReadyPod("pod-b"), newReadyPod("pod-c"), stuck)
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(newNamedPodMetrics("pod-a", "pod-b", "pod-c", "pod-d"), nil)
+	manager := scaler.NewScalingManager(kubeClient, scaleClient, newTestRESTMapper(), mockMetricsClient, predictorServer.URL)
+
+	hpa := createTestHPAModifier()
+	hpa.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}}
+	hpa.Spec.CPUThreshold = 2
+	hpa.Spec.MemoryThreshold = 4
+	assert.NoError(t, manager.ScaleWorkload(context.Background(), hpa))
+	assert.Equal(t, int32(3), hpa.Status.ReadyReplicas)
+	assert.Equal(t, int32(0), hpa.Status.StartingReplicas)
+	// 按 3 个就绪 Pod 承担的负载缩容
+	assert.Equal(t, int32(1), replicas)
+
+	// 同一个 Pod 刚启动时暂停缩容
+	stuck.Status.StartTime = &metav1.Time{Time: time.Now()}
+	_, err := kubeClient.CoreV1().Pods("default").UpdateStatus(context.Background(), stuck, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	replicas = 4
+	hpa.Status.LastScaledTime = nil
+	assert.NoError(t, manager.ScaleWorkload(context.Background(), hpa))
+	assert.Equal(t, int32(1), hpa.Status.StartingReplicas)
+	assert.Equal(t, int32(4), replicas)
+}
+
+func TestCollectMetricsCountsReadyReplicas(t *testing.T) {
+	unready := newReadyPod("pod-b")
+	unready.Status.Conditions[0].Status = corev1.ConditionFalse
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(newNamedPodMetrics("pod-a", "pod-b"), nil)
+	manager := scaler.NewScalingManager(newTestKubeClient(newReadyPod("pod-a"), unready), &fakescale.FakeScaleClient{},
+		newTestRESTMapper(), mockMetricsClient, "")
+
+	hpa := createTestHPAModifier()
+	hpa.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}}
+	_, _, err := manager.CollectMetrics(context.Background(), hpa)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), hpa.Status.ReadyReplicas)
+}
+
+func TestScaleWorkloadReadsScaleOnce(t *testing.T) {
+	predictorServer := newPredictorServer([]float64{0.75})
+	defer predictorServer.Close()
+
+	replicas := int32(4)
+	scaleClient := newLiveScaleClient(&replicas)
+	kubeClient := newTestKubeClient()
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(newNamedPodMetrics("pod-a", "pod-b"), nil)
+	manager := scaler.NewScalingManager(kubeClient, scaleClient, newTestRESTMapper(), mockMetricsClient, predictorServer.URL)
+
+	hpa := createTestHPAModifier()
+	assert.NoError(t, manager.ScaleWorkload(context.Background(), hpa))
+	hpa.Status.LastScaledTime = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+	assert.NoError(t, manager.ScaleWorkload(context.Background(), hpa))
+
+	// 每次调谐只读取一次 /scale 子资源，discovery 的结果在调谐之间复用
+	var scaleGets, discoveries int
+	for _, action := range scaleClient.Actions() {
+		if action.GetVerb() == "get" {
+			scaleGets++
+		}
+	}
+	for _, action := range kubeClient.Actions() {
+		if action.GetVerb() == "get" && action.GetResource().Resource == "resource" {
+			discoveries++
+		}
+	}
+	assert.Equal(t, 2, scaleGets)
+	assert.Equal(t, 1, discoveries)
+}
@@ -0,0 +1,116 @@
+package scaler_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	fakescale "k8s.io/client-go/scale/fake"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+
+	"yemo.info/auto-scaling-system/internal/scaler"
+)
+
+// newStartedPod 创建在 created 创建、startup 后就绪的 Pod
+func newStartedPod(name string, created time.Time, startup time.Duration) *corev1.Pod {
+	pod := newReadyPod(name)
+	pod.UID = types.UID(name)
+	pod.CreationTimestamp = metav1.NewTime(created)
+	pod.Status.Conditions[0].LastTransitionTime = metav1.NewTime(created.Add(startup))
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "app", Ready: true}}
+	return pod
+}
+
+func TestCollectMetricsLearnsPodStartupLatency(t *testing.T) {
+	now := time.Now()
+	restarted := newStartedPod("restarted", now.Add(-2*time.Hour), 10*time.Minute)
+	restarted.Status.ContainerStatuses[0].RestartCount = 1
+	starting := newStartedPod("starting", now.Add(-time.Minute), 0)
+	starting.Status.Conditions[0].Status = corev1.ConditionFalse
+	late := newStartedPod("late", now.Add(-30*time.Minute), 90*time.Second)
+	kubeClient := newTestKubeClient(newStartedPod("early", now.Add(-time.Hour), 30*time.Second), late, restarted, starting)
+
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(&metricsv1beta1.PodMetricsList{}, nil)
+	manager := scaler.NewScalingManager(kubeClient, &fakescale.FakeScaleClient{}, newTestRESTMapper(), mockMetricsClient, "")
+	hpa := createTestHPAModifier()
+	hpa.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}}
+
+	// 容器重启过和未就绪的 Pod 不计入，启动耗时在 30s 和 90s 之间插值后取 P90
+	_, _, _ = manager.CollectMetrics(context.Background(), hpa)
+	if assert.NotNil(t, hpa.Status.PodStartupLatency) {
+		assert.InDelta(t, 84, hpa.Status.PodStartupLatency.Seconds(), 1e-6)
+	}
+
+	// 已记录的 Pod 之后重新就绪时不再计入
+	late.Status.Conditions[0].LastTransitionTime = metav1.NewTime(now.Add(-time.Minute))
+	_, err := kubeClient.CoreV1().Pods("default").UpdateStatus(context.Background(), late, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	_, _, _ = manager.CollectMetrics(context.Background(), hpa)
+	assert.InDelta(t, 84, hpa.Status.PodStartupLatency.Seconds(), 1e-6)
+}
+
+func TestCollectMetricsKeepsRecentPodStartups(t *testing.T) {
+	now := time.Now()
+	kubeClient := newTestKubeClient(
+		newStartedPod("fast", now.Add(-40*time.Minute), 30*time.Second),
+		newStartedPod("slow", now.Add(-50*time.Minute), 10*time.Minute+30*time.Second),
+	)
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(&metricsv1beta1.PodMetricsList{}, nil)
+	manager := scaler.NewScalingManager(kubeClient, &fakescale.FakeScaleClient{}, newTestRESTMapper(), mockMetricsClient, "")
+	hpa := createTestHPAModifier()
+	hpa.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}}
+
+	// 同一采样间隔内就绪的 Pod 按各自的启动耗时计算 P90，不先取平均
+	_, _, _ = manager.CollectMetrics(context.Background(), hpa)
+	if assert.NotNil(t, hpa.Status.PodStartupLatency) {
+		assert.InDelta(t, 30+0.9*600, hpa.Status.PodStartupLatency.Seconds(), 1e-6)
+	}
+
+	// 只保留最近的 64 个 Pod，更早的启动耗时被覆盖
+	for _, name := range []string{"fast", "slow"} {
+		assert.NoError(t, kubeClient.CoreV1().Pods("default").Delete(context.Background(), name, metav1.DeleteOptions{}))
+	}
+	for i := 0; i < 64; i++ {
+		pod := newStartedPod(fmt.Sprintf("pod-%d", i), now.Add(-time.Hour), time.Minute)
+		_, err := kubeClient.CoreV1().Pods("default").Create(context.Background(), pod, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+	_, _, _ = manager.CollectMetrics(context.Background(), hpa)
+	assert.InDelta(t, 60, hpa.Status.PodStartupLatency.Seconds(), 1e-6)
+}
+
+func TestPreWarmUsesLearnedStartupLatency(t *testing.T) {
+	predictorServer := newPredictorServer(preWarmForecast(16, 9))
+	defer predictorServer.Close()
+
+	// 两个 Pod 都在创建 3 分钟后就绪
+	now := time.Now()
+	kubeClient := newTestKubeClient(
+		newStartedPod("nginx-deployment-9d9b49c9b-64sbk", now.Add(-2*time.Hour), 3*time.Minute),
+		newStartedPod("nginx-deployment-9d9b49c9b-x7k2p", now.Add(-time.Hour), 3*time.Minute),
+	)
+	replicas := int32(2)
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(createTestPodMetrics(), nil)
+	manager := scaler.NewScalingManager(kubeClient, newLiveScaleClient(&replicas), newTestRESTMapper(),
+		mockMetricsClient, predictorServer.URL)
+	manager.HistorySource = &periodicHistorySource{}
+
+	hpa := createTestHPAModifier()
+	hpa.Spec.CPUThreshold = 0.5
+	assert.NoError(t, manager.ScaleWorkload(context.Background(), hpa))
+
+	// 扩容时间提前学习到的 3 分钟，而不是默认的 1 分钟
+	assert.Equal(t, int32(2), replicas)
+	if assert.NotNil(t, hpa.Status.NextPreWarm) {
+		assert.WithinDuration(t, now.Add(10*time.Minute), hpa.Status.NextPreWarm.PeakTime.Time, 5*time.Second)
+		assert.WithinDuration(t, now.Add(7*time.Minute), hpa.Status.NextPreWarm.ScaleUpTime.Time, 5*time.Second)
+	}
+}
@@ -0,0 +1,135 @@
+package scaler_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	fakescale "k8s.io/client-go/scale/fake"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+	"yemo.info/auto-scaling-system/internal/scaler"
+)
+
+func float64Ptr(value float64) *float64 {
+	return &value
+}
+
+func TestCalculateDesiredReplicasTolerance(t *testing.T) {
+	// 预测服务不可用，按实时用量计算
+	predictorServer := newPredictorServer([]float64{0})
+	predictorServer.Close()
+	manager := scaler.NewScalingManager(newTestKubeClient(), &fakescale.FakeScaleClient{}, newTestRESTMapper(),
+		&MockMetricsClient{}, predictorServer.URL)
+
+	hpa := createTestHPAModifier()
+	hpa.Spec.MaxReplicas = 20
+	hpa.Spec.CPUThreshold = 0.5
+	hpa.Status.CurrentReplicas = 10
+
+	for _, tc := range []struct {
+		name       string
+		tolerance  *float64
+		hysteresis *float64
+		cpuUsage   float64
+		expected   int32
+		suppressed int32
+	}{
+		// 默认容差 0.1：负载比率 1.04 不扩容
+		{name: "within default tolerance", cpuUsage: 0.52, expected: 10, suppressed: 1},
+		{name: "above tolerance", cpuUsage: 0.6, expected: 12, suppressed: 1},
+		{name: "below tolerance", cpuUsage: 0.425, expected: 9, suppressed: 1},
+		// 缩容需要负载比率低于 0.8
+		{name: "within hysteresis", hysteresis: float64Ptr(0.1), cpuUsage: 0.425, expected: 10, suppressed: 2},
+		{name: "zero tolerance", tolerance: float64Ptr(0), cpuUsage: 0.52, expected: 11, suppressed: 2},
+	} {
+		hpa.Spec.Tolerance = tc.tolerance
+		hpa.Spec.ScaleDownHysteresis = tc.hysteresis
+		desiredReplicas, _, err := manager.CalculateDesiredReplicas(context.Background(), hpa, tc.cpuUsage, 0.1)
+		assert.NoError(t, err, tc.name)
+		assert.Equal(t, tc.expected, desiredReplicas, tc.name)
+		assert.Equal(t, tc.suppressed, hpa.Status.SuppressedDecisions, tc.name)
+	}
+}
+
+func TestCalculateDesiredReplicasMetricSpecTolerance(t *testing.T) {
+	predictorServer := newPredictorServer([]float64{0})
+	predictorServer.Close()
+	manager := scaler.NewScalingManager(newTestKubeClient(), &fakescale.FakeScaleClient{}, newTestRESTMapper(),
+		&MockMetricsClient{}, predictorServer.URL)
+	manager.CustomMetrics = newFakeCustomMetrics(100, 110)
+
+	hpa := newMetricSpecHPAModifier()
+	hpa.Spec.Metrics = []autoscalingv2.MetricSpec{{
+		Type: autoscalingv2.PodsMetricSourceType,
+		Pods: &autoscalingv2.PodsMetricSource{
+			Metric: autoscalingv2.MetricIdentifier{Name: "http_requests_per_second"},
+			Target: autoscalingv2.MetricTarget{Type: autoscalingv2.AverageValueMetricType, AverageValue: resource.NewQuantity(100, resource.DecimalSI)},
+		},
+	}}
+	hpa.Status.CurrentReplicas = 10
+
+	// 平均 105 / 目标 100 在容差带内，保持 10 个副本
+	desiredReplicas, _, err := manager.CalculateDesiredReplicas(context.Background(), hpa, 0.1, 0.1)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(10), desiredReplicas)
+	assert.Equal(t, int32(1), hpa.Status.SuppressedDecisions)
+}
+
+func TestSuppressedDecisionsSameRuleForBothPaths(t *testing.T) {
+	predictorServer := newPredictorServer([]float64{0})
+	predictorServer.Close()
+	manager := scaler.NewScalingManager(newTestKubeClient(), &fakescale.FakeScaleClient{}, newTestRESTMapper(),
+		&MockMetricsClient{}, predictorServer.URL)
+
+	// 同样的阈值分别通过 cpuThreshold/memoryThreshold 和 spec.metrics 配置
+	classic := createTestHPAModifier()
+	classic.Spec.MaxReplicas = 20
+	classic.Spec.CPUThreshold = 0.5
+	classic.Spec.MemoryThreshold = 1
+	metricSpec := classic.DeepCopy()
+	metricSpec.Spec.Metrics = []autoscalingv2.MetricSpec{
+		{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name:   corev1.ResourceCPU,
+				Target: autoscalingv2.MetricTarget{Type: autoscalingv2.AverageValueMetricType, AverageValue: resource.NewMilliQuantity(500, resource.DecimalSI)},
+			},
+		},
+		{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name:   corev1.ResourceMemory,
+				Target: autoscalingv2.MetricTarget{Type: autoscalingv2.AverageValueMetricType, AverageValue: resource.NewQuantity(1<<30, resource.BinarySI)},
+			},
+		},
+	}
+
+	for _, tc := range []struct {
+		name        string
+		cpuUsage    float64
+		memoryUsage float64
+		expected    int32
+		suppressed  bool
+	}{
+		// CPU 负载比率 1.04 在容差带内，内存低于容差带但取较大的建议，保持 10 个副本
+		{name: "cpu within tolerance", cpuUsage: 0.52, memoryUsage: 0.5, expected: 10, suppressed: true},
+		// CPU 在容差带内，但内存超出容差带，仍然扩容，不计入
+		{name: "memory above tolerance", cpuUsage: 0.52, memoryUsage: 1.2, expected: 12, suppressed: false},
+		// 建议本来就等于当前副本数，不计入
+		{name: "exactly on target", cpuUsage: 0.5, memoryUsage: 0.5, expected: 10, suppressed: false},
+		{name: "above tolerance", cpuUsage: 0.6, memoryUsage: 0.5, expected: 12, suppressed: false},
+	} {
+		for _, hpa := range []*autoscalingv1.HPAModifier{classic, metricSpec} {
+			hpa.Status.CurrentReplicas = 10
+			hpa.Status.SuppressedDecisions = 0
+			desiredReplicas, _, err := manager.CalculateDesiredReplicas(context.Background(), hpa, tc.cpuUsage, tc.memoryUsage)
+			assert.NoError(t, err, tc.name)
+			assert.Equal(t, tc.expected, desiredReplicas, tc.name)
+			assert.Equal(t, tc.suppressed, hpa.Status.SuppressedDecisions == 1, tc.name)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package scaler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakescale "k8s.io/client-go/scale/fake"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+
+	autoscalingv1 "yemo.info/auto-scaling-system/api/v1"
+	"yemo.info/auto-scaling-system/internal/scaler"
+)
+
+// newReadyPod 创建一小时前启动、已就绪的 Pod
+func newReadyPod(name string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "nginx"}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			StartTime:  &metav1.Time{Time: time.Now().Add(-time.Hour)},
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+// newRequestPod 创建带 app 容器 requests 的 Pod，withSidecar 时添加一个没有 requests 的 sidecar
+func newRequestPod(name string, withSidecar bool) *corev1.Pod {
+	pod := newReadyPod(name)
+	pod.Spec.Containers[0].Resources.Requests = corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("500m"),
+		corev1.ResourceMemory: resource.MustParse("1Gi"),
+	}
+	if withSidecar {
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: "sidecar"})
+	}
+	return pod
+}
+
+// newContainerMetrics 返回一个容器的用量
+func newContainerMetrics(name, cpu, memory string) metricsv1beta1.ContainerMetrics {
+	return metricsv1beta1.ContainerMetrics{
+		Name: name,
+		Usage: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(cpu),
+			corev1.ResourceMemory: resource.MustParse(memory),
+		},
+	}
+}
+
+// newUtilizationPodMetrics pod-a 的 app 用 250m/512Mi、sidecar 用 100m/64Mi，pod-b 的 app 用 500m/1Gi；
+// pod-c 的 Pod 已删除，不应计入
+func newUtilizationPodMetrics() *metricsv1beta1.PodMetricsList {
+	return &metricsv1beta1.PodMetricsList{Items: []metricsv1beta1.PodMetrics{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-a", Namespace: "default"},
+			Containers: []metricsv1beta1.ContainerMetrics{
+				newContainerMetrics("app", "250m", "512Mi"),
+				newContainerMetrics("sidecar", "100m", "64Mi"),
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-b", Namespace: "default"},
+			Containers: []metricsv1beta1.ContainerMetrics{newContainerMetrics("app", "500m", "1Gi")},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-c", Namespace: "default"},
+			Containers: []metricsv1beta1.ContainerMetrics{newContainerMetrics("app", "2", "4Gi")},
+		},
+	}}
+}
+
+func averageUtilization(percent int32) *int32 {
+	return &percent
+}
+
+func TestCollectMetricsUtilization(t *testing.T) {
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(newUtilizationPodMetrics(), nil)
+	kubeClient := newTestKubeClient(newRequestPod("pod-a", true), newRequestPod("pod-b", false))
+	manager := scaler.NewScalingManager(kubeClient, &fakescale.FakeScaleClient{}, newTestRESTMapper(), mockMetricsClient, "")
+
+	hpa := createTestHPAModifier()
+	hpa.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}}
+	hpa.Spec.ThresholdMode = autoscalingv1.ThresholdModeUtilization
+
+	// 默认与 HPA 相同，sidecar 没有 requests 时无法计算利用率
+	_, _, err := manager.CollectMetrics(context.Background(), hpa)
+	assert.True(t, scaler.IsTargetError(err))
+	assert.Contains(t, err.Error(), "pod-a/sidecar")
+
+	// 忽略 sidecar：CPU (250m + 500m) / 1000m，内存 (512Mi + 1Gi) / 2Gi
+	hpa.Spec.MissingRequests = autoscalingv1.MissingRequestsIgnoreContainer
+	cpuUtilization, memoryUtilization, err := manager.CollectMetrics(context.Background(), hpa)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.75, cpuUtilization, 1e-9)
+	assert.InDelta(t, 0.75, memoryUtilization, 1e-9)
+
+	// 利用率阈值 0.5 时负载比率为 1.5
+	hpa.Spec.CPUThreshold = 0.5
+	hpa.Status.CurrentReplicas = 2
+	desiredReplicas, loadRatio, err := manager.CalculateDesiredReplicas(context.Background(), hpa, cpuUtilization, memoryUtilization)
+	assert.NoError(t, err)
+	assert.InDelta(t, 1.5, loadRatio, 1e-9)
+	assert.Equal(t, int32(3), desiredReplicas)
+}
+
+func TestCalculateDesiredReplicasResourceUtilizationTarget(t *testing.T) {
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(newUtilizationPodMetrics(), nil)
+	kubeClient := newTestKubeClient(newRequestPod("pod-a", true), newRequestPod("pod-b", false))
+	manager := scaler.NewScalingManager(kubeClient, &fakescale.FakeScaleClient{}, newTestRESTMapper(), mockMetricsClient, "http://127.0.0.1:0")
+
+	// thresholdMode 为 Absolute 时，利用率目标按 requests 重新计算
+	hpa := createTestHPAModifier()
+	hpa.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "nginx"}}
+	hpa.Spec.MissingRequests = autoscalingv1.MissingRequestsIgnoreContainer
+	hpa.Spec.Metrics = []autoscalingv2.MetricSpec{{
+		Type: autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricSource{
+			Name:   corev1.ResourceCPU,
+			Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: averageUtilization(50)},
+		},
+	}}
+	hpa.Status.CurrentReplicas = 2
+
+	// 利用率 75% / 目标 50% * 2 = 3
+	desiredReplicas, loadRatio, err := manager.CalculateDesiredReplicas(context.Background(), hpa, 0.95, 1.2)
+	assert.NoError(t, err)
+	assert.InDelta(t, 1.5, loadRatio, 1e-9)
+	assert.Equal(t, int32(3), desiredReplicas)
+}
+
+func TestScaleWorkloadResourceUtilizationTargetCollectsOnce(t *testing.T) {
+	mockMetricsClient := &MockMetricsClient{}
+	mockMetricsClient.On("GetPodMetrics", "default", "app=nginx").Return(newUtilizationPodMetrics(), nil)
+	kubeClient := newTestKubeClient(newRequestPod("pod-a", true), newRequestPod("pod-b", false))
+	replicas := int32(2)
+	manager := scaler.NewScalingManager(kubeClient, newLiveScaleClient(&replicas), newTestRESTMapper(), mockMetricsClient, "http://127.0.0.1:0")
+
+	hpa := createTestHPAModifier()
+	hpa.Spec.MissingRequests = autoscalingv1.MissingRequestsIgnoreContainer
+	hpa.Spec.Metrics = []autoscalingv2.MetricSpec{{
+		Type: autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricSource{
+			Name:   corev1.ResourceCPU,
+			Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: averageUtilization(50)},
+		},
+	}}
+
+	// 利用率从本次调谐已收集的 Pod 指标计算，不再重新读取指标和 Pod
+	assert.NoError(t, manager.ScaleWorkload(context.Background(), hpa))
+	assert.Equal(t, int32(3), replicas)
+	mockMetricsClient.AssertNumberOfCalls(t, "GetPodMetrics", 1)
+	var podLists int
+	for _, action := range kubeClient.Actions() {
+		if action.GetVerb() == "list" && action.GetResource().Resource == "pods" {
+			podLists++
+		}
+	}
+	assert.Equal(t, 1, podLists)
+}